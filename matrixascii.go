@@ -0,0 +1,138 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Matrices drawn as text, a row per line with '#' for set and '.' for clear
+// Polygon vertexes can be overlaid as '@' on set pixels and 'o' on clear ones
+
+const (
+	asciiSet         = '#'
+	asciiClear       = '.'
+	asciiVertexSet   = '@'
+	asciiVertexClear = 'o'
+)
+
+type ASCIIOption func(*asciiOptions)
+
+type asciiOptions struct {
+	rulers   bool
+	polygons []*Polygon
+}
+
+// Col numbers above, a digit per line, and row numbers down the left
+func WithRulers() ASCIIOption {
+	return func(ao *asciiOptions) {
+		ao.rulers = true
+	}
+}
+
+// Marks the vertexes of every ring, those outside the matrix are skipped
+func WithVertexes(polygons ...*Polygon) ASCIIOption {
+	return func(ao *asciiOptions) {
+		ao.polygons = append(ao.polygons, polygons...)
+	}
+}
+
+// Rows x cols and how many are set, FormatASCII draws the bits
+func (m *MatrixBitSet) String() string {
+	return fmt.Sprintf("%d x %d, %d set", m.R, m.C, m.Count())
+}
+
+func (m *MatrixBitSet) FormatASCII(opts ...ASCIIOption) string {
+	ao := &asciiOptions{}
+	for _, opt := range opts {
+		opt(ao)
+	}
+	vertexes := NewMatrixBitSet(m.C, m.R)
+	for _, p := range ao.polygons {
+		for _, ring := range p.rings() {
+			for _, mp := range ring {
+				if mp.r < m.R && mp.c < m.C {
+					vertexes.set(m.index(mp.r, mp.c))
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	margin := 0
+	if ao.rulers {
+		margin = len(fmt.Sprint(m.LastRow()))
+		m.writeColRulers(&b, margin)
+	}
+	for r := uint(0); r < m.R; r++ {
+		if ao.rulers {
+			fmt.Fprintf(&b, "%*d ", margin, r)
+		}
+		for c := uint(0); c < m.C; c++ {
+			n := m.index(r, c)
+			switch {
+			case vertexes.test(n) && m.test(n):
+				b.WriteByte(asciiVertexSet)
+			case vertexes.test(n):
+				b.WriteByte(asciiVertexClear)
+			case m.test(n):
+				b.WriteByte(asciiSet)
+			default:
+				b.WriteByte(asciiClear)
+			}
+		}
+		if r+1 < m.R {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Most significant digit first, the higher digits only where they change
+func (m *MatrixBitSet) writeColRulers(b *strings.Builder, margin int) {
+	digits := len(fmt.Sprint(m.LastCol()))
+	for d := digits - 1; d >= 0; d-- {
+		place := uint(1)
+		for i := 0; i < d; i++ {
+			place *= 10
+		}
+		b.WriteString(strings.Repeat(" ", margin+1))
+		for c := uint(0); c < m.C; c++ {
+			if d > 0 && c%place != 0 {
+				b.WriteByte(' ')
+				continue
+			}
+			fmt.Fprint(b, c/place%10)
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// The inverse of FormatASCII, vertex marks read as the pixel under them.
+// Blank lines at either end and the space around each row are ignored,
+// so fixtures can be indented raw strings
+func ParseASCII(s string) (*MatrixBitSet, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, fmt.Errorf("no rows to parse")
+	}
+	cols := uint(len(lines[0]))
+	m := NewMatrixBitSet(cols, uint(len(lines)))
+	for r, line := range lines {
+		if uint(len(line)) != cols {
+			return nil, fmt.Errorf("row %d has %d cols, expected %d", r, len(line), cols)
+		}
+		for c := 0; c < len(line); c++ {
+			switch line[c] {
+			case asciiSet, asciiVertexSet:
+				m.set(m.index(uint(r), uint(c)))
+			case asciiClear, asciiVertexClear:
+			default:
+				return nil, fmt.Errorf("row %d col %d: unexpected %q", r, c, line[c])
+			}
+		}
+	}
+	return m, nil
+}
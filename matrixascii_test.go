@@ -0,0 +1,75 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func TestParseASCIIRoundTrip(t *testing.T) {
+	m, err := ParseASCII(`
+		.##.
+		#..#
+		.##.
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.R != 3 || m.C != 4 || m.Count() != 6 || !m.Test(1, 0) || m.Test(1, 1) {
+		t.Errorf("Unexpected matrix\n%s", m.FormatASCII())
+	}
+	if expected := ".##.\n#..#\n.##."; m.FormatASCII() != expected {
+		t.Errorf("Expected\n%s\nreceived\n%s", expected, m.FormatASCII())
+	}
+	again, _ := ParseASCII(m.FormatASCII())
+	if !sameBits(again, m) {
+		t.Error("Expected FormatASCII to parse back to the same matrix")
+	}
+	if summary := m.String(); summary != "3 x 4, 6 set" {
+		t.Errorf("Expected a one line summary, received %q", summary)
+	}
+}
+
+func TestParseASCIIErrors(t *testing.T) {
+	for _, bad := range []string{"", "  \n ", "##\n#", "#x#"} {
+		if _, err := ParseASCII(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestFormatASCIIRulers(t *testing.T) {
+	m := NewMatrixBitSet(12, 2)
+	m.Set(0, 0).Set(1, 11)
+	expected := "" +
+		"  0         1 \n" +
+		"  012345678901\n" +
+		"0 #...........\n" +
+		"1 ...........#"
+	if received := m.FormatASCII(WithRulers()); received != expected {
+		t.Errorf("Expected\n%s\nreceived\n%s", expected, received)
+	}
+}
+
+func TestFormatASCIIVertexes(t *testing.T) {
+	m, _ := ParseASCII(`
+		.....
+		.###.
+		.###.
+		.....
+	`)
+	polygon := &Polygon{Outer: LinearRing{
+		NewMatrixPos(1, 1, m.C), NewMatrixPos(1, 3, m.C), NewMatrixPos(3, 3, m.C),
+		NewMatrixPos(3, 1, m.C), NewMatrixPos(1, 1, m.C),
+	}}
+	expected := "" +
+		".....\n" +
+		".@#@.\n" +
+		".###.\n" +
+		".o.o."
+	received := m.FormatASCII(WithVertexes(polygon))
+	if received != expected {
+		t.Errorf("Expected\n%s\nreceived\n%s", expected, received)
+	}
+	if parsed, _ := ParseASCII(received); !sameBits(parsed, m) {
+		t.Error("Expected the vertex marks to parse as the pixels under them")
+	}
+}
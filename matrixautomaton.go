@@ -0,0 +1,264 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Life-like cellular automata, outer totalistic rules over the 8 neighbors
+// Rows are stepped 64 cells at a time, the neighbor counts kept bit sliced
+// as 4 words holding bit 0, 1, 2 and 3 of every cell's count
+
+// Bit k of Birth turns a dead cell on with k live neighbors
+// bit k of Survive keeps a live cell on with k live neighbors
+type Rule struct {
+	Birth   uint16
+	Survive uint16
+}
+
+// Conway's Life, B3/S23
+var LifeRule = Rule{Birth: 1 << 3, Survive: 1<<2 | 1<<3}
+
+// Accepts B3/S23 in either order and case, or the older 23/3 survive/birth
+func ParseRule(s string) (Rule, error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(s)), "/")
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("rule %q isn't of the form B3/S23", s)
+	}
+	var rule Rule
+	var err error
+	switch {
+	case strings.HasPrefix(parts[0], "B") && strings.HasPrefix(parts[1], "S"):
+		rule.Birth, err = ruleCounts(parts[0][1:])
+		if err == nil {
+			rule.Survive, err = ruleCounts(parts[1][1:])
+		}
+	case strings.HasPrefix(parts[0], "S") && strings.HasPrefix(parts[1], "B"):
+		rule.Survive, err = ruleCounts(parts[0][1:])
+		if err == nil {
+			rule.Birth, err = ruleCounts(parts[1][1:])
+		}
+	default:
+		rule.Survive, err = ruleCounts(parts[0])
+		if err == nil {
+			rule.Birth, err = ruleCounts(parts[1])
+		}
+	}
+	if err != nil {
+		return Rule{}, fmt.Errorf("rule %q: %v", s, err)
+	}
+	return rule, nil
+}
+
+func ruleCounts(digits string) (uint16, error) {
+	var counts uint16
+	for _, d := range digits {
+		if d < '0' || d > '8' {
+			return 0, fmt.Errorf("%q isn't a neighbor count", d)
+		}
+		counts |= 1 << uint(d-'0')
+	}
+	return counts, nil
+}
+
+func (rule Rule) String() string {
+	var b strings.Builder
+	b.WriteString("B")
+	for k := uint(0); k <= 8; k++ {
+		if rule.Birth&(1<<k) != 0 {
+			fmt.Fprint(&b, k)
+		}
+	}
+	b.WriteString("/S")
+	for k := uint(0); k <= 8; k++ {
+		if rule.Survive&(1<<k) != 0 {
+			fmt.Fprint(&b, k)
+		}
+	}
+	return b.String()
+}
+
+// What lies past the edges of the matrix
+type EdgeMode int
+
+const (
+	// Cells past the edge are always dead
+	EdgeDead EdgeMode = iota
+	// Cells past the edge copy the nearest edge cell
+	EdgeBounded
+	// The matrix wraps around, top to bottom and left to right
+	EdgeToroidal
+)
+
+// Steps a copy of a matrix one generation at a time, swapping
+// between two sets of aligned rows so stepping allocates nothing
+type Automaton struct {
+	Rule       Rule
+	Edge       EdgeMode
+	Generation uint
+	cols       uint
+	current    [][]uint64
+	next       [][]uint64
+	west, east []uint64
+	dead       []uint64
+}
+
+func NewAutomaton(m *MatrixBitSet, rule Rule, edge EdgeMode) *Automaton {
+	a := &Automaton{Rule: rule, Edge: edge, cols: m.C, current: m.toRows()}
+	a.next = make([][]uint64, m.R)
+	for r := range a.next {
+		a.next[r] = make([]uint64, m.rowWordCount())
+	}
+	a.west = make([]uint64, 3*m.rowWordCount())
+	a.east = make([]uint64, 3*m.rowWordCount())
+	a.dead = make([]uint64, m.rowWordCount())
+	return a
+}
+
+// One generation of m, returns a new M2
+func (m *MatrixBitSet) Step(rule Rule, edge EdgeMode) *MatrixBitSet {
+	a := NewAutomaton(m, rule, edge)
+	a.Step()
+	return a.Matrix()
+}
+
+// The current generation, returns a new M2
+func (a *Automaton) Matrix() *MatrixBitSet {
+	return fromRows(a.current, a.cols)
+}
+
+// Runs n generations
+func (a *Automaton) Run(n uint) *Automaton {
+	for i := uint(0); i < n; i++ {
+		a.Step()
+	}
+	return a
+}
+
+func (a *Automaton) Step() *Automaton {
+	rows := len(a.current)
+	if rows == 0 || a.cols == 0 {
+		a.Generation++
+		return a
+	}
+	words := len(a.current[0])
+	west, east := a.west, a.east
+	for r := 0; r < rows; r++ {
+		above, below := a.neighborRow(r-1), a.neighborRow(r+1)
+		alive := a.current[r]
+		// the 3 rows shifted, so bit c holds col c-1 or c+1
+		a.shiftWest(above, west[:words])
+		a.shiftWest(alive, west[words:2*words])
+		a.shiftWest(below, west[2*words:])
+		a.shiftEast(above, east[:words])
+		a.shiftEast(alive, east[words:2*words])
+		a.shiftEast(below, east[2*words:])
+		out := a.next[r]
+		for w := 0; w < words; w++ {
+			var s0, s1, s2, s3 uint64
+			for _, x := range [8]uint64{
+				above[w], below[w],
+				west[w], west[words+w], west[2*words+w],
+				east[w], east[words+w], east[2*words+w],
+			} {
+				// ripple carry add of one bit into every cell's count
+				c0 := s0 & x
+				s0 ^= x
+				c1 := s1 & c0
+				s1 ^= c0
+				c2 := s2 & c1
+				s2 ^= c1
+				s3 |= c2
+			}
+			out[w] = a.Rule.apply(alive[w], [4]uint64{s0, s1, s2, s3}) & rowMask(a.cols, w)
+		}
+	}
+	a.current, a.next = a.next, a.current
+	a.Generation++
+	return a
+}
+
+// Which cells are on next, given which are on now and their counts
+func (rule Rule) apply(alive uint64, count [4]uint64) uint64 {
+	var result uint64
+	for k := uint(0); k <= 8; k++ {
+		born, survives := rule.Birth&(1<<k) != 0, rule.Survive&(1<<k) != 0
+		if !born && !survives {
+			continue
+		}
+		eq := allBits
+		for b := uint(0); b < 4; b++ {
+			if k&(1<<b) != 0 {
+				eq &= count[b]
+			} else {
+				eq &^= count[b]
+			}
+		}
+		if born {
+			result |= eq &^ alive
+		}
+		if survives {
+			result |= eq & alive
+		}
+	}
+	return result
+}
+
+// Row r, which may be just past either edge
+func (a *Automaton) neighborRow(r int) []uint64 {
+	rows := len(a.current)
+	switch {
+	case r >= 0 && r < rows:
+		return a.current[r]
+	case a.Edge == EdgeToroidal:
+		return a.current[(r+rows)%rows]
+	case a.Edge == EdgeBounded && r < 0:
+		return a.current[0]
+	case a.Edge == EdgeBounded:
+		return a.current[rows-1]
+	}
+	return a.dead
+}
+
+// dst bit c holds src bit c-1, the cell to the west
+func (a *Automaton) shiftWest(src, dst []uint64) {
+	var carry uint64
+	for w := range src {
+		dst[w] = src[w]<<1 | carry
+		carry = src[w] >> (wordSize - 1)
+	}
+	switch a.Edge {
+	case EdgeToroidal:
+		dst[0] |= boolWord(testRow(src, a.cols-1))
+	case EdgeBounded:
+		dst[0] |= src[0] & 1
+	}
+}
+
+// dst bit c holds src bit c+1, the cell to the east
+func (a *Automaton) shiftEast(src, dst []uint64) {
+	for w := range src {
+		dst[w] = src[w] >> 1
+		if w+1 < len(src) {
+			dst[w] |= src[w+1] << (wordSize - 1)
+		}
+	}
+	last := a.cols - 1
+	var edge bool
+	switch a.Edge {
+	case EdgeToroidal:
+		edge = testRow(src, 0)
+	case EdgeBounded:
+		edge = testRow(src, last)
+	}
+	if edge {
+		dst[last>>log2WordSize] |= 1 << (last & (wordSize - 1))
+	}
+}
+
+func boolWord(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package matrixbitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// One generation a cell at a time
+func bruteStep(m *MatrixBitSet, rule Rule, edge EdgeMode) *MatrixBitSet {
+	result := NewMatrixBitSet(m.C, m.R)
+	rows, cols := int(m.R), int(m.C)
+	at := func(r, c int) bool {
+		switch edge {
+		case EdgeToroidal:
+			r, c = (r+rows)%rows, (c+cols)%cols
+		case EdgeBounded:
+			r, c = maxInt(0, minInt(r, rows-1)), maxInt(0, minInt(c, cols-1))
+		}
+		return r >= 0 && c >= 0 && r < rows && c < cols && m.Test(uint(r), uint(c))
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			count := uint(0)
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					if (dr != 0 || dc != 0) && at(r+dr, c+dc) {
+						count++
+					}
+				}
+			}
+			counts := rule.Birth
+			if at(r, c) {
+				counts = rule.Survive
+			}
+			if counts&(1<<count) != 0 {
+				result.Set(uint(r), uint(c))
+			}
+		}
+	}
+	return result
+}
+
+func TestParseRule(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b36/s23", "B36/S23"},
+		{"S23/B3", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{"B2/S", "B2/S"},
+		{"B012345678/S012345678", "B012345678/S012345678"},
+	} {
+		rule, err := ParseRule(tc.in)
+		if err != nil || rule.String() != tc.out {
+			t.Errorf("%q: expected %q, received %q, %v", tc.in, tc.out, rule, err)
+		}
+	}
+	for _, bad := range []string{"B3S23", "B9/S23", "B3/S2x", ""} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestStepAgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(13))
+	rules := []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B1/S012345678", "B0/S8"}
+	for trial := 0; trial < 60; trial++ {
+		rule, _ := ParseRule(rules[trial%len(rules)])
+		w, h := uint(1+rnd.Intn(140)), uint(1+rnd.Intn(8))
+		m := randomMatrix(rnd, w, h, 0.35)
+		for _, edge := range []EdgeMode{EdgeDead, EdgeBounded, EdgeToroidal} {
+			a := NewAutomaton(m, rule, edge)
+			expected := m
+			for gen := 0; gen < 3; gen++ {
+				a.Step()
+				expected = bruteStep(expected, rule, edge)
+				if !sameBits(a.Matrix(), expected) {
+					t.Fatalf("%v, %d x %d, edge %d, generation %d: differs from brute force", rule, h, w, edge, a.Generation)
+				}
+			}
+		}
+	}
+}
+
+func TestGliderWrapsAround(t *testing.T) {
+	m := shapeFromRows([]string{
+		".#......",
+		"..#.....",
+		"###.....",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	})
+	// a glider moves one cell diagonally every 4 generations
+	a := NewAutomaton(m, LifeRule, EdgeToroidal).Run(4 * 8)
+	if !sameBits(a.Matrix(), m) || a.Generation != 32 {
+		t.Error("Expected the glider back where it started")
+	}
+	if !sameBits(m.Step(LifeRule, EdgeDead), bruteStep(m, LifeRule, EdgeDead)) {
+		t.Error("Expected Step to match a single generation")
+	}
+}
@@ -40,12 +40,19 @@ func NewMatrixBounds(m *MatrixBitSet, points LinearRing) *MatrixBounds {
 	return bounds
 }
 
+// Min and Max rows, cols are all inclusive, a single pixel has Min == Max.
+// Width and Height count the pixels spanned, so 1 for a single pixel
 func (mb *MatrixBounds) Width() uint {
-	return mb.MaxC - mb.MinC
+	return mb.MaxC - mb.MinC + 1
 }
 
 func (mb *MatrixBounds) Height() uint {
-	return mb.MaxR - mb.MinR
+	return mb.MaxR - mb.MinR + 1
+}
+
+// Pixels spanned by the rectangle, ignoring any polygon vertexes
+func (mb *MatrixBounds) Area() uint {
+	return mb.Width() * mb.Height()
 }
 
 // These are all relative to an UpperLeft Origin
@@ -107,3 +114,100 @@ func (mb *MatrixBounds) panicIfOutside() {
 	mb.M.panicOverSized(mb.right)
 	mb.M.panicOverSized(mb.bottom)
 }
+
+// Bounds of the rectangle [minR, minC] - [maxR, maxC] inclusive
+// The vertexes are its corners, so NInside and friends see a rectangle
+func NewRectBounds(m *MatrixBitSet, minR, minC, maxR, maxC uint) *MatrixBounds {
+	bounds := &MatrixBounds{
+		M:    m,
+		MinR: minR,
+		MinC: minC,
+		MaxR: maxR,
+		MaxC: maxC,
+	}
+	bounds.vertx = []uint{minC, maxC, maxC, minC}
+	bounds.verty = []uint{minR, minR, maxR, maxR}
+	bounds.left, bounds.top = m.index(minR, minC), m.index(minR, maxC)
+	bounds.right, bounds.bottom = m.index(maxR, maxC), m.index(maxR, minC)
+	return bounds
+}
+
+// Do the rectangles share at least one pixel?
+func (mb *MatrixBounds) Overlaps(other *MatrixBounds) bool {
+	return mb.MinR <= other.MaxR && other.MinR <= mb.MaxR &&
+		mb.MinC <= other.MaxC && other.MinC <= mb.MaxC
+}
+
+// The pixels in both rectangles, false when they don't overlap
+func (mb *MatrixBounds) Intersect(other *MatrixBounds) (*MatrixBounds, bool) {
+	if !mb.Overlaps(other) {
+		return nil, false
+	}
+	return NewRectBounds(mb.M, maxUint(mb.MinR, other.MinR), maxUint(mb.MinC, other.MinC),
+		minUint(mb.MaxR, other.MaxR), minUint(mb.MaxC, other.MaxC)), true
+}
+
+// The smallest rectangle holding both
+func (mb *MatrixBounds) Union(other *MatrixBounds) *MatrixBounds {
+	return NewRectBounds(mb.M, minUint(mb.MinR, other.MinR), minUint(mb.MinC, other.MinC),
+		maxUint(mb.MaxR, other.MaxR), maxUint(mb.MaxC, other.MaxC))
+}
+
+// Grows the rectangle by margin pixels on every side
+// stopping at the edges of the matrix
+func (mb *MatrixBounds) Expand(margin uint) *MatrixBounds {
+	minR, minC := uint(0), uint(0)
+	if mb.MinR > margin {
+		minR = mb.MinR - margin
+	}
+	if mb.MinC > margin {
+		minC = mb.MinC - margin
+	}
+	maxR, maxC := mb.MaxR+margin, mb.MaxC+margin
+	if maxR < mb.MaxR || maxR > mb.M.LastRow() {
+		maxR = mb.M.LastRow()
+	}
+	if maxC < mb.MaxC || maxC > mb.M.LastCol() {
+		maxC = mb.M.LastCol()
+	}
+	return NewRectBounds(mb.M, minR, minC, maxR, maxC)
+}
+
+// The part of the rectangle that lies within m, tied to m
+// false when none of it does
+func (mb *MatrixBounds) ClipTo(m *MatrixBitSet) (*MatrixBounds, bool) {
+	if m.IsEmpty() || mb.MinR > m.LastRow() || mb.MinC > m.LastCol() {
+		return nil, false
+	}
+	return NewRectBounds(m, mb.MinR, mb.MinC, minUint(mb.MaxR, m.LastRow()), minUint(mb.MaxC, m.LastCol())), true
+}
+
+// Calls fn with the index of every pixel in the rectangle, row by row
+// stops early when fn returns false
+func (mb *MatrixBounds) EachN(fn func(n uint) bool) {
+	if mb.MinR > mb.MaxR || mb.MinC > mb.MaxC {
+		return
+	}
+	mb.M.panicPastMatrix(mb.MaxR, mb.MaxC)
+	for r := mb.MinR; r <= mb.MaxR; r++ {
+		for n := mb.M.index(r, mb.MinC); n <= mb.M.index(r, mb.MaxC); n++ {
+			if !fn(n) {
+				return
+			}
+		}
+	}
+}
+
+func minUint(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint(a, b uint) uint {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func TestRectAlgebra(t *testing.T) {
+	m := NewMatrixBitSet(20, 10)
+	a := NewRectBounds(m, 1, 1, 4, 5)
+	b := NewRectBounds(m, 3, 4, 8, 15)
+	if a.Height() != 4 || a.Width() != 5 || a.Area() != 20 {
+		t.Errorf("Expected 4 x 5, received %d x %d area %d", a.Height(), a.Width(), a.Area())
+	}
+	if single := NewRectBounds(m, 2, 2, 2, 2); single.Width() != 1 || single.Height() != 1 || single.Area() != 1 {
+		t.Errorf("Expected a single pixel, received %d x %d", single.Height(), single.Width())
+	}
+	if !a.Overlaps(b) || !b.Overlaps(a) {
+		t.Error("Expected a and b to overlap")
+	}
+	if both, ok := a.Intersect(b); !ok || both.MinR != 3 || both.MinC != 4 || both.MaxR != 4 || both.MaxC != 5 {
+		t.Errorf("Expected [3, 4] - [4, 5], received %+v", both)
+	}
+	if _, ok := a.Intersect(NewRectBounds(m, 5, 0, 9, 19)); ok {
+		t.Error("Expected rectangles sharing no rows not to intersect")
+	}
+	if either := a.Union(b); either.MinR != 1 || either.MinC != 1 || either.MaxR != 8 || either.MaxC != 15 {
+		t.Errorf("Expected [1, 1] - [8, 15], received %+v", either)
+	}
+	if grown := a.Expand(3); grown.MinR != 0 || grown.MinC != 0 || grown.MaxR != 7 || grown.MaxC != 8 {
+		t.Errorf("Expected [0, 0] - [7, 8], received %+v", grown)
+	}
+	if grown := b.Expand(5); grown.MaxR != 9 || grown.MaxC != 19 {
+		t.Errorf("Expected Expand to stop at [9, 19], received [%d, %d]", grown.MaxR, grown.MaxC)
+	}
+	small := NewMatrixBitSet(6, 6)
+	if clipped, ok := b.ClipTo(small); !ok || clipped.MaxR != 5 || clipped.MaxC != 5 || clipped.M != small {
+		t.Errorf("Expected [3, 4] - [5, 5] in the small matrix, received %+v", clipped)
+	}
+	if _, ok := NewRectBounds(m, 7, 7, 8, 8).ClipTo(small); ok {
+		t.Error("Expected nothing left after clipping")
+	}
+}
+
+func TestRectEachN(t *testing.T) {
+	m := NewMatrixBitSet(20, 10)
+	rect := NewRectBounds(m, 2, 3, 4, 6)
+	visited := uint(0)
+	rect.EachN(func(n uint) bool {
+		m.SetN(n)
+		visited++
+		if !rect.NInside(n) {
+			t.Errorf("Expected %s within the rectangle", m.point(n))
+		}
+		return true
+	})
+	if visited != rect.Area() || m.Count() != rect.Area() {
+		t.Errorf("Expected %d visits, received %d", rect.Area(), visited)
+	}
+	bounds, _ := m.BoundsOfSets()
+	if bounds.MinR != rect.MinR || bounds.MinC != rect.MinC || bounds.MaxR != rect.MaxR || bounds.MaxC != rect.MaxC {
+		t.Errorf("Expected the filled bounds to match the rectangle, received %+v", bounds)
+	}
+	stopped := 0
+	rect.EachN(func(n uint) bool {
+		stopped++
+		return stopped < 3
+	})
+	if stopped != 3 {
+		t.Errorf("Expected EachN to stop after 3, received %d", stopped)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected EachN past the last col to panic")
+		}
+	}()
+	NewRectBounds(m, 2, 18, 3, 20).EachN(func(n uint) bool {
+		return true
+	})
+}
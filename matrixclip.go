@@ -0,0 +1,933 @@
+package matrixbitset
+
+import (
+	"container/heap"
+	"math/big"
+	"sort"
+)
+
+// Boolean operations on polygons in vector space, no rasterizing.
+// A sweep line (Martinez, Rueda and Feito) runs across both sides' edges
+// by col, splitting edges only where they meet a neighbour in the sweep,
+// and keeps or drops each piece by which side of the other polygon it
+// runs along. Every test is exact: input vertexes are int64 grid points
+// and diagonal edges that cross between grid points meet at exact
+// rationals, so results are ClipPolygons, ToPolygon brings them back
+// to the grid
+
+type ClipOp int
+
+const (
+	ClipUnion ClipOp = iota
+	ClipIntersection
+	ClipDifference
+	ClipXor
+)
+
+// A vertex of a clipped polygon, at [R, C] like a MatrixPos.
+// Both are exact fractions, whole numbers wherever edges meet on the grid
+type ClipPoint struct {
+	R, C *big.Rat
+}
+
+func gridClipPoint(r, c int64) ClipPoint {
+	return ClipPoint{big.NewRat(r, 1), big.NewRat(c, 1)}
+}
+
+func (pt ClipPoint) String() string {
+	return "[" + pt.R.RatString() + ", " + pt.C.RatString() + "]"
+}
+
+func (pt ClipPoint) equal(other ClipPoint) bool {
+	return comparePoints(pt, other) == 0
+}
+
+// ClipPoints hold pointers, so maps key them by value
+func (pt ClipPoint) key() string {
+	return pt.R.RatString() + "," + pt.C.RatString()
+}
+
+func (pt ClipPoint) sub(other ClipPoint) ClipPoint {
+	return ClipPoint{subRat(pt.R, other.R), subRat(pt.C, other.C)}
+}
+
+type ClipRing []ClipPoint
+
+// Outer runs counter clockwise as displayed, holes clockwise,
+// the same as a Normalized Polygon
+type ClipPolygon struct {
+	Outer ClipRing
+	Holes []ClipRing
+}
+
+func (p *Polygon) Union(other *Polygon) []*ClipPolygon {
+	return ClipPolygons(ClipUnion, []*Polygon{p}, []*Polygon{other})
+}
+
+func (p *Polygon) Intersection(other *Polygon) []*ClipPolygon {
+	return ClipPolygons(ClipIntersection, []*Polygon{p}, []*Polygon{other})
+}
+
+func (p *Polygon) Difference(other *Polygon) []*ClipPolygon {
+	return ClipPolygons(ClipDifference, []*Polygon{p}, []*Polygon{other})
+}
+
+func (p *Polygon) Xor(other *Polygon) []*ClipPolygon {
+	return ClipPolygons(ClipXor, []*Polygon{p}, []*Polygon{other})
+}
+
+// Combines two sets of polygons, like those from ExtractAllPolygons.
+// The polygons within a set must not overlap each other.
+// Neither set is modified
+func ClipPolygons(op ClipOp, a, b []*Polygon) []*ClipPolygon {
+	queue := make(clipQueue, 0, 256)
+	var subjectBox, otherBox clipBox
+	contour := 0
+	for _, side := range []struct {
+		polygons []*Polygon
+		box      *clipBox
+		subject  bool
+	}{{a, &subjectBox, true}, {b, &otherBox, false}} {
+		side.box.reset()
+		for _, p := range side.polygons {
+			contour++
+			for _, ring := range p.rings() {
+				queue.addRing(ring, side.subject, contour, side.box)
+			}
+		}
+	}
+	heap.Init(&queue)
+	return connectClipEdges(subdivideClipEdges(&queue, op, subjectBox, otherBox))
+}
+
+// Twice the signed area, > 0 is counter clockwise as displayed like LinearRing
+func (cr ClipRing) Area2() *big.Rat {
+	area, term := new(big.Rat), new(big.Rat)
+	for i := 0; i+1 < len(cr); i++ {
+		area.Add(area, term.Mul(cr[i].R, cr[i+1].C))
+		area.Sub(area, term.Mul(cr[i+1].R, cr[i].C))
+	}
+	return area
+}
+
+// Twice the area of the outer ring less its holes
+func (cp *ClipPolygon) Area2() *big.Rat {
+	area := new(big.Rat).Abs(cp.Outer.Area2())
+	for _, hole := range cp.Holes {
+		area.Sub(area, new(big.Rat).Abs(hole.Area2()))
+	}
+	return area
+}
+
+// The polygon with every vertex strided by stride, false when
+// any vertex falls between grid points. Rectilinear inputs, like
+// the rings from TraceShell or ExtractCrackPolygons, always fit
+func (cp *ClipPolygon) ToPolygon(stride uint) (*Polygon, bool) {
+	outer, ok := cp.Outer.toRing(stride)
+	if !ok {
+		return nil, false
+	}
+	p := &Polygon{Outer: outer, Holes: make([]LinearRing, 0, len(cp.Holes))}
+	for _, h := range cp.Holes {
+		hole, ok := h.toRing(stride)
+		if !ok {
+			return nil, false
+		}
+		p.Holes = append(p.Holes, hole)
+	}
+	return p, true
+}
+
+func (cr ClipRing) toRing(stride uint) (LinearRing, bool) {
+	ring := make(LinearRing, 0, len(cr))
+	for _, pt := range cr {
+		if !pt.R.IsInt() || !pt.C.IsInt() || pt.R.Sign() < 0 || pt.C.Sign() < 0 ||
+			!pt.R.Num().IsUint64() || !pt.C.Num().IsUint64() {
+			return nil, false
+		}
+		ring = append(ring, NewMatrixPos(uint(pt.R.Num().Uint64()), uint(pt.C.Num().Uint64()), stride))
+	}
+	return ring, true
+}
+
+// What an edge contributes once it's known to overlap another
+const (
+	clipNormal = iota
+	clipNonContributing
+	clipSameTransition
+	clipDifferentTransition
+)
+
+// An input edge, left end first, with both ends on the grid. Pieces
+// cut from it lie exactly on its line, so they're measured against it
+type clipEdge struct {
+	left, right ClipPoint
+	// right less left
+	dir ClipPoint
+}
+
+func newClipEdge(from, to MatrixPos) *clipEdge {
+	left, right := gridClipPoint(int64(from.r), int64(from.c)), gridClipPoint(int64(to.r), int64(to.c))
+	if comparePoints(left, right) > 0 {
+		left, right = right, left
+	}
+	return &clipEdge{left: left, right: right, dir: right.sub(left)}
+}
+
+// One end of an edge, left is the end the sweep reaches first
+type sweepEvent struct {
+	point   ClipPoint
+	left    bool
+	other   *sweepEvent
+	edge    *clipEdge
+	subject bool
+	contour int
+	kind    int
+	// crossing the edge upwards leaves its own polygon / is outside the other
+	inOut      bool
+	otherInOut bool
+	// the closest edge below that's in the result
+	prevInResult *sweepEvent
+	// > 0 when the result is just above the edge in the sweep,
+	// < 0 just below, 0 when the edge isn't in the result
+	transition int
+}
+
+func (se *sweepEvent) inResult() bool {
+	return se.transition != 0
+}
+
+func (se *sweepEvent) vertical() bool {
+	return se.edge.dir.C.Sign() == 0
+}
+
+// Which side of the edge's line pt is on, > 0 when the edge passes
+// below it looking along the sweep and 0 when pt is on the line
+func (se *sweepEvent) side(pt ClipPoint) int {
+	return signedArea(se.edge.left, se.edge.right, pt)
+}
+
+func (se *sweepEvent) below(pt ClipPoint) bool {
+	return se.side(pt) > 0
+}
+
+// Points by col, then row, the order the sweep reaches them
+func comparePoints(p1, p2 ClipPoint) int {
+	if c := cmpRat(p1.C, p2.C); c != 0 {
+		return c
+	}
+	return cmpRat(p1.R, p2.R)
+}
+
+// Events by point, right ends before left ends at the same point
+func compareEvents(e1, e2 *sweepEvent) int {
+	p1, p2 := e1.point, e2.point
+	if c := comparePoints(p1, p2); c != 0 {
+		return c
+	}
+	if e1.left != e2.left {
+		if e1.left {
+			return 1
+		}
+		return -1
+	}
+	if e1.side(e2.other.point) != 0 {
+		if !e1.below(e2.other.point) {
+			return 1
+		}
+		return -1
+	}
+	if !e1.subject && e2.subject {
+		return 1
+	}
+	return -1
+}
+
+// Edges in the sweep by which lies below the other
+func compareSegments(le1, le2 *sweepEvent) int {
+	if le1 == le2 {
+		return 0
+	}
+	if le1.side(le2.point) != 0 || le1.side(le2.other.point) != 0 {
+		// not collinear
+		if le1.point.equal(le2.point) {
+			if le1.below(le2.other.point) {
+				return -1
+			}
+			return 1
+		}
+		if cmpRat(le1.point.C, le2.point.C) == 0 {
+			if cmpRat(le1.point.R, le2.point.R) < 0 {
+				return -1
+			}
+			return 1
+		}
+		// which was inserted first, going by the far end when
+		// the later one starts on the earlier
+		if compareEvents(le1, le2) == 1 {
+			pt := le1.point
+			if le2.side(pt) == 0 {
+				pt = le1.other.point
+			}
+			if !le2.below(pt) {
+				return -1
+			}
+			return 1
+		}
+		pt := le2.point
+		if le1.side(pt) == 0 {
+			pt = le2.other.point
+		}
+		if le1.below(pt) {
+			return -1
+		}
+		return 1
+	}
+	if le1.subject != le2.subject {
+		if le1.subject {
+			return -1
+		}
+		return 1
+	}
+	if le1.point.equal(le2.point) {
+		if le1.other.point.equal(le2.other.point) {
+			return 0
+		}
+		if le1.contour > le2.contour {
+			return 1
+		}
+		return -1
+	}
+	if compareEvents(le1, le2) == 1 {
+		return 1
+	}
+	return -1
+}
+
+type clipQueue []*sweepEvent
+
+func (cq clipQueue) Len() int {
+	return len(cq)
+}
+
+func (cq clipQueue) Less(i, j int) bool {
+	return compareEvents(cq[i], cq[j]) < 0
+}
+
+func (cq clipQueue) Swap(i, j int) {
+	cq[i], cq[j] = cq[j], cq[i]
+}
+
+func (cq *clipQueue) Push(x interface{}) {
+	*cq = append(*cq, x.(*sweepEvent))
+}
+
+func (cq *clipQueue) Pop() interface{} {
+	old := *cq
+	event := old[len(old)-1]
+	*cq = old[:len(old)-1]
+	return event
+}
+
+// Both ends of every edge, rings may run either way and needn't be closed
+func (cq *clipQueue) addRing(ring LinearRing, subject bool, contour int, box *clipBox) {
+	for i := range ring {
+		from, to := ring[i], ring[(i+1)%len(ring)]
+		if samePoint(from, to) {
+			continue
+		}
+		edge := newClipEdge(from, to)
+		e1 := &sweepEvent{point: edge.left, left: true, edge: edge, subject: subject, contour: contour}
+		e2 := &sweepEvent{point: edge.right, edge: edge, subject: subject, contour: contour}
+		e1.other, e2.other = e2, e1
+		box.extend(edge.right)
+		*cq = append(*cq, e1, e2)
+	}
+}
+
+// Right of every vertex of one side, so the sweep can stop early,
+// nil when the side has no vertexes
+type clipBox struct {
+	maxC *big.Rat
+}
+
+func (cb *clipBox) reset() {
+	cb.maxC = nil
+}
+
+func (cb *clipBox) extend(pt ClipPoint) {
+	if cb.maxC == nil || cmpRat(pt.C, cb.maxC) > 0 {
+		cb.maxC = pt.C
+	}
+}
+
+func (cb clipBox) before(pt ClipPoint) bool {
+	return cb.maxC == nil || cmpRat(pt.C, cb.maxC) > 0
+}
+
+// Runs the sweep, returning the events in the order they were handled
+func subdivideClipEdges(queue *clipQueue, op ClipOp, subjectBox, otherBox clipBox) []*sweepEvent {
+	sweep := make(sweepLine, 0, 64)
+	sorted := make([]*sweepEvent, 0, queue.Len())
+	for queue.Len() > 0 {
+		event := heap.Pop(queue).(*sweepEvent)
+		sorted = append(sorted, event)
+		// nothing past either side can be in an intersection,
+		// nothing past the subject in a difference
+		if (op == ClipIntersection && (subjectBox.before(event.point) || otherBox.before(event.point))) ||
+			(op == ClipDifference && subjectBox.before(event.point)) {
+			break
+		}
+		if event.left {
+			at := sweep.insert(event)
+			prev, next := sweep.at(at-1), sweep.at(at+1)
+			event.computeFields(prev, op)
+			if next != nil && possibleIntersection(event, next, queue) == 2 {
+				event.computeFields(prev, op)
+				next.computeFields(event, op)
+			}
+			if prev != nil && possibleIntersection(prev, event, queue) == 2 {
+				prev.computeFields(sweep.at(sweep.index(prev)-1), op)
+				event.computeFields(prev, op)
+			}
+		} else {
+			left := event.other
+			at := sweep.index(left)
+			if at < 0 {
+				continue
+			}
+			prev, next := sweep.at(at-1), sweep.at(at+1)
+			sweep.remove(at)
+			if prev != nil && next != nil {
+				possibleIntersection(prev, next, queue)
+			}
+		}
+	}
+	return sorted
+}
+
+// Left events ordered by compareSegments, bottom first
+type sweepLine []*sweepEvent
+
+func (sl *sweepLine) insert(event *sweepEvent) int {
+	s := *sl
+	at := sort.Search(len(s), func(i int) bool {
+		return compareSegments(s[i], event) > 0
+	})
+	s = append(s, nil)
+	copy(s[at+1:], s[at:])
+	s[at] = event
+	*sl = s
+	return at
+}
+
+// Splitting edges can leave the order only nearly sorted,
+// so fall back to a scan when the search misses
+func (sl sweepLine) index(event *sweepEvent) int {
+	at := sort.Search(len(sl), func(i int) bool {
+		return compareSegments(sl[i], event) >= 0
+	})
+	if at < len(sl) && sl[at] == event {
+		return at
+	}
+	for i, e := range sl {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func (sl sweepLine) at(i int) *sweepEvent {
+	if i < 0 || i >= len(sl) {
+		return nil
+	}
+	return sl[i]
+}
+
+func (sl *sweepLine) remove(at int) {
+	*sl = append((*sl)[:at], (*sl)[at+1:]...)
+}
+
+// inOut, otherInOut and whether the edge is in the result,
+// from the edge just below it in the sweep
+func (se *sweepEvent) computeFields(prev *sweepEvent, op ClipOp) {
+	if prev == nil {
+		se.inOut, se.otherInOut = false, true
+	} else {
+		if se.subject == prev.subject {
+			se.inOut, se.otherInOut = !prev.inOut, prev.otherInOut
+		} else {
+			se.inOut = !prev.otherInOut
+			if prev.vertical() {
+				se.otherInOut = !prev.inOut
+			} else {
+				se.otherInOut = prev.inOut
+			}
+		}
+		if !prev.inResult() || prev.vertical() {
+			se.prevInResult = prev.prevInResult
+		} else {
+			se.prevInResult = prev
+		}
+	}
+	se.transition = 0
+	if se.contributes(op) {
+		se.transition = se.resultTransition(op)
+	}
+}
+
+func (se *sweepEvent) contributes(op ClipOp) bool {
+	switch se.kind {
+	case clipNormal:
+		switch op {
+		case ClipIntersection:
+			return !se.otherInOut
+		case ClipUnion:
+			return se.otherInOut
+		case ClipDifference:
+			return se.subject == se.otherInOut
+		case ClipXor:
+			return true
+		}
+	case clipSameTransition:
+		return op == ClipIntersection || op == ClipUnion
+	case clipDifferentTransition:
+		return op == ClipDifference
+	}
+	return false
+}
+
+// Is the result just above the edge (1) or just below it (-1)
+func (se *sweepEvent) resultTransition(op ClipOp) int {
+	thisIn, thatIn := !se.inOut, !se.otherInOut
+	if se.kind != clipNormal {
+		// the other side's copy of this edge may sit either side of it in
+		// the sweep, so only this side's own interior is known, the result
+		// is on it unless a hole's edge is being cut away
+		if !se.subject && se.kind == clipDifferentTransition {
+			thisIn = !thisIn
+		}
+		if thisIn {
+			return 1
+		}
+		return -1
+	}
+	var in bool
+	switch op {
+	case ClipIntersection:
+		in = thisIn && thatIn
+	case ClipUnion:
+		in = thisIn || thatIn
+	case ClipXor:
+		in = thisIn != thatIn
+	case ClipDifference:
+		if se.subject {
+			in = thisIn && !thatIn
+		} else {
+			in = thatIn && !thisIn
+		}
+	}
+	if in {
+		return 1
+	}
+	return -1
+}
+
+// Splits se1 and se2 where they meet. Returns 0 when they don't need
+// splitting, 1 for a crossing, 2 when they overlap from the same left
+// end, which changes their fields, and 3 for other overlaps
+func possibleIntersection(se1, se2 *sweepEvent, queue *clipQueue) int {
+	inter := segmentIntersection(se1, se2)
+	switch {
+	case len(inter) == 0:
+		return 0
+	case len(inter) == 1 && (se1.point.equal(se2.point) || se1.other.point.equal(se2.other.point)):
+		// meeting at an end of both
+		return 0
+	case len(inter) == 2 && se1.subject == se2.subject:
+		// overlapping edges of the same side
+		return 0
+	case len(inter) == 1:
+		if !se1.point.equal(inter[0]) && !se1.other.point.equal(inter[0]) {
+			divideSegment(se1, inter[0], queue)
+		}
+		if !se2.point.equal(inter[0]) && !se2.other.point.equal(inter[0]) {
+			divideSegment(se2, inter[0], queue)
+		}
+		return 1
+	}
+
+	// overlapping, the distinct ends in sweep order
+	events := make([]*sweepEvent, 0, 4)
+	leftCoincide, rightCoincide := se1.point.equal(se2.point), se1.other.point.equal(se2.other.point)
+	if !leftCoincide {
+		if compareEvents(se1, se2) == 1 {
+			events = append(events, se2, se1)
+		} else {
+			events = append(events, se1, se2)
+		}
+	}
+	if !rightCoincide {
+		if compareEvents(se1.other, se2.other) == 1 {
+			events = append(events, se2.other, se1.other)
+		} else {
+			events = append(events, se1.other, se2.other)
+		}
+	}
+	if leftCoincide {
+		// the shared part counts once
+		se2.kind = clipNonContributing
+		if se2.inOut == se1.inOut {
+			se1.kind = clipSameTransition
+		} else {
+			se1.kind = clipDifferentTransition
+		}
+		if !rightCoincide {
+			divideSegment(events[1].other, events[0].point, queue)
+		}
+		return 2
+	}
+	if rightCoincide {
+		divideSegment(events[0], events[1].point, queue)
+		return 3
+	}
+	if events[0] != events[3].other {
+		// neither holds the other
+		divideSegment(events[0], events[1].point, queue)
+		divideSegment(events[1], events[2].point, queue)
+		return 3
+	}
+	// one holds the other
+	divideSegment(events[0], events[1].point, queue)
+	divideSegment(events[3].other, events[2].point, queue)
+	return 3
+}
+
+// Cuts the edge at pt into two, queueing the new ends
+func divideSegment(se *sweepEvent, pt ClipPoint, queue *clipQueue) {
+	r := &sweepEvent{point: pt, other: se, edge: se.edge, subject: se.subject, contour: se.contour}
+	l := &sweepEvent{point: pt, left: true, other: se.other, edge: se.edge, subject: se.subject, contour: se.contour}
+	se.other.other = l
+	se.other = r
+	heap.Push(queue, l)
+	heap.Push(queue, r)
+}
+
+// Where the pieces se1 and se2 meet, one point for a crossing or touch,
+// two for the ends of an overlap. Crossings come from the input edges'
+// lines, so every piece of the same two lines meets at the same point
+func segmentIntersection(se1, se2 *sweepEvent) []ClipPoint {
+	a1, b1 := se1.edge.left, se2.edge.left
+	va, vb := se1.edge.dir, se2.edge.dir
+	e := b1.sub(a1)
+	lo1, hi1 := se1.point, se1.other.point
+	lo2, hi2 := se2.point, se2.other.point
+
+	if kross := crossClip(va, vb); kross.Sign() != 0 {
+		s := new(big.Rat).Quo(crossClip(e, vb), kross)
+		pt := ClipPoint{
+			new(big.Rat).Add(a1.R, new(big.Rat).Mul(va.R, s)),
+			new(big.Rat).Add(a1.C, new(big.Rat).Mul(va.C, s)),
+		}
+		if comparePoints(pt, lo1) < 0 || comparePoints(pt, hi1) > 0 ||
+			comparePoints(pt, lo2) < 0 || comparePoints(pt, hi2) > 0 {
+			return nil
+		}
+		return []ClipPoint{pt}
+	}
+	if crossClip(e, va).Sign() != 0 {
+		// parallel, not collinear
+		return nil
+	}
+	// along the same line the sweep order is the order along it
+	lo, hi := lo1, hi1
+	if comparePoints(lo2, lo) > 0 {
+		lo = lo2
+	}
+	if comparePoints(hi2, hi) < 0 {
+		hi = hi2
+	}
+	switch comparePoints(lo, hi) {
+	case 1:
+		return nil
+	case 0:
+		return []ClipPoint{lo}
+	}
+	return []ClipPoint{lo, hi}
+}
+
+// A result edge, walked with the result on its right as
+// displayed, so outer rings come out counter clockwise
+type clipLink struct {
+	from, to ClipPoint
+	event    *sweepEvent
+}
+
+func (cl clipLink) dir() ClipPoint {
+	return cl.to.sub(cl.from)
+}
+
+// Links the result edges into rings, taking the sharpest turn towards
+// the result wherever several leave the same point, so polygons touching
+// at a point stay apart. A hole touching its outer ring comes out joined
+// to it, so rings are split wherever they pass a point twice. Each hole
+// goes to the outer ring of whatever result edge the sweep found just
+// below where it starts
+func connectClipEdges(sorted []*sweepEvent) []*ClipPolygon {
+	links := make([]clipLink, 0, len(sorted)/2)
+	leaving := make(map[string][]int)
+	for _, e := range sorted {
+		if !e.left || !e.inResult() {
+			continue
+		}
+		link := clipLink{from: e.point, to: e.other.point, event: e}
+		if e.transition > 0 {
+			link.from, link.to = link.to, link.from
+		}
+		leaving[link.from.key()] = append(leaving[link.from.key()], len(links))
+		links = append(links, link)
+	}
+
+	used := make([]bool, len(links))
+	pieces := make([][]int, 0)
+	for first := range links {
+		if used[first] {
+			continue
+		}
+		walk := make([]int, 0, 16)
+		at := first
+		for {
+			used[at] = true
+			walk = append(walk, at)
+			next := -1
+			for _, candidate := range leaving[links[at].to.key()] {
+				if candidate == at || (used[candidate] && candidate != first) {
+					continue
+				}
+				if next < 0 || towardResult(links[at].dir(), links[candidate].dir(), links[next].dir()) {
+					next = candidate
+				}
+			}
+			if next < 0 || next == first {
+				break
+			}
+			at = next
+		}
+		pieces = append(pieces, splitClipWalk(walk, links)...)
+	}
+	// by where each starts in the sweep, so whatever a hole
+	// starts above is placed before it
+	starts := make([]int, len(pieces))
+	for i, piece := range pieces {
+		starts[i] = piece[0]
+		for _, li := range piece {
+			starts[i] = minInt(starts[i], li)
+		}
+	}
+	order := make([]int, len(pieces))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return starts[order[i]] < starts[order[j]]
+	})
+
+	ringOf := make(map[*sweepEvent]int, len(links))
+	// the outer ring each hole belongs to, -1 for outer rings
+	parents := make([]int, len(pieces))
+	rings := make([]ClipRing, len(pieces))
+	for _, id := range order {
+		ring := ClipRing{links[pieces[id][0]].from}
+		for _, li := range pieces[id] {
+			ringOf[links[li].event] = id
+			ring = append(ring, links[li].to)
+		}
+		rings[id] = ring
+		parents[id] = -1
+		if ring.Area2().Sign() < 0 {
+			parents[id] = holeParent(links[starts[id]].event, ringOf, parents)
+		}
+	}
+
+	polygons := make([]*ClipPolygon, 0)
+	outers := make(map[int]*ClipPolygon)
+	for id, ring := range rings {
+		if parents[id] >= 0 || ring.Area2().Sign() < 0 {
+			continue
+		}
+		if outer := ring.simplified(1); len(outer) >= 4 {
+			outers[id] = &ClipPolygon{Outer: outer, Holes: make([]ClipRing, 0)}
+			polygons = append(polygons, outers[id])
+		}
+	}
+	for id, ring := range rings {
+		if p, ok := outers[parents[id]]; ok {
+			if hole := ring.simplified(-1); len(hole) >= 4 {
+				p.Holes = append(p.Holes, hole)
+			}
+		}
+	}
+	return polygons
+}
+
+// Cuts a closed walk of links into loops that each pass a point once
+func splitClipWalk(walk []int, links []clipLink) [][]int {
+	pieces := make([][]int, 0, 1)
+	stack := make([]int, 0, len(walk))
+	at := make(map[string]int, len(walk))
+	for _, li := range walk {
+		from := links[li].from.key()
+		if pos, ok := at[from]; ok {
+			piece := append([]int(nil), stack[pos:]...)
+			for _, x := range piece {
+				delete(at, links[x].from.key())
+			}
+			pieces = append(pieces, piece)
+			stack = stack[:pos]
+		}
+		at[from] = len(stack)
+		stack = append(stack, li)
+	}
+	if len(stack) > 0 {
+		pieces = append(pieces, stack)
+	}
+	return pieces
+}
+
+// Is turning from in to a further towards the result than turning
+// from in to b? Towards it beats going straight beats turning away
+// beats doubling back
+func towardResult(in, a, b ClipPoint) bool {
+	rank := func(d ClipPoint) int {
+		cross := crossClip(in, d).Sign()
+		switch {
+		case cross < 0:
+			return 3
+		case cross == 0 && dotClip(in, d).Sign() > 0:
+			return 2
+		case cross > 0:
+			return 1
+		}
+		return 0
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra > rb
+	}
+	// same half plane, a is further if b turns towards the result to reach it
+	return crossClip(b, a).Sign() < 0
+}
+
+// The result lies between a hole's first edge and the result edge
+// below it, which is either the outer ring or another of its holes
+func holeParent(start *sweepEvent, ringOf map[*sweepEvent]int, parents []int) int {
+	for below := start.prevInResult; below != nil; below = below.prevInResult {
+		if id, ok := ringOf[below]; ok {
+			if parents[id] >= 0 {
+				return parents[id]
+			}
+			return id
+		}
+	}
+	return -1
+}
+
+// Closed, without repeated or collinear points, the sign of Area2 as asked
+func (cr ClipRing) simplified(sign int) ClipRing {
+	kept := make(ClipRing, 0, len(cr))
+	for _, pt := range cr {
+		for len(kept) > 1 && signedArea(kept[len(kept)-2], kept[len(kept)-1], pt) == 0 {
+			kept = kept[:len(kept)-1]
+		}
+		if len(kept) == 0 || !kept[len(kept)-1].equal(pt) {
+			kept = append(kept, pt)
+		}
+	}
+	// across the seam, the closing point goes here too
+	start := 0
+	for len(kept)-start > 2 {
+		last := len(kept) - 1
+		if signedArea(kept[last-1], kept[last], kept[start]) == 0 {
+			kept = kept[:last]
+		} else if signedArea(kept[last], kept[start], kept[start+1]) == 0 {
+			start++
+		} else {
+			break
+		}
+	}
+	if len(kept)-start < 3 {
+		return nil
+	}
+	ring := append(kept[start:], kept[start])
+	if ring.Area2().Sign()*sign < 0 {
+		for left, right := 0, len(ring)-1; left < right; left, right = left+1, right-1 {
+			ring[left], ring[right] = ring[right], ring[left]
+		}
+	}
+	return ring
+}
+
+// > 0 when p0, p1, p2 turn one way, < 0 the other, 0 collinear.
+// Grid points small enough not to overflow are done in int64
+func signedArea(p0, p1, p2 ClipPoint) int {
+	var v [6]int64
+	small := true
+	for i, x := range [6]*big.Rat{p0.R, p0.C, p1.R, p1.C, p2.R, p2.C} {
+		v[i], small = smallInt(x)
+		if !small {
+			return crossClip(p0.sub(p2), p1.sub(p2)).Sign()
+		}
+	}
+	area := (v[1]-v[5])*(v[2]-v[4]) - (v[3]-v[5])*(v[0]-v[4])
+	switch {
+	case area > 0:
+		return 1
+	case area < 0:
+		return -1
+	}
+	return 0
+}
+
+// x when it's a whole number under 2^30 either way,
+// where products of differences fit an int64
+func smallInt(x *big.Rat) (int64, bool) {
+	if !x.IsInt() || !x.Num().IsInt64() {
+		return 0, false
+	}
+	v := x.Num().Int64()
+	return v, v > -1<<30 && v < 1<<30
+}
+
+// Rat.Sub normalizes even whole numbers
+func subRat(a, b *big.Rat) *big.Rat {
+	if a.IsInt() && b.IsInt() {
+		return new(big.Rat).SetInt(new(big.Int).Sub(a.Num(), b.Num()))
+	}
+	return new(big.Rat).Sub(a, b)
+}
+
+// Rat.Cmp scales both by the other's denominator even when they're 1
+func cmpRat(a, b *big.Rat) int {
+	if a.IsInt() && b.IsInt() {
+		return a.Num().Cmp(b.Num())
+	}
+	return a.Cmp(b)
+}
+
+func crossClip(a, b ClipPoint) *big.Rat {
+	if aR, ok := smallInt(a.R); ok {
+		aC, okC := smallInt(a.C)
+		bR, okBR := smallInt(b.R)
+		bC, okBC := smallInt(b.C)
+		if okC && okBR && okBC {
+			return big.NewRat(aC*bR-aR*bC, 1)
+		}
+	}
+	cross := new(big.Rat).Mul(a.C, b.R)
+	return cross.Sub(cross, new(big.Rat).Mul(a.R, b.C))
+}
+
+func dotClip(a, b ClipPoint) *big.Rat {
+	dot := new(big.Rat).Mul(a.R, b.R)
+	return dot.Add(dot, new(big.Rat).Mul(a.C, b.C))
+}
@@ -0,0 +1,175 @@
+package matrixbitset
+
+import (
+	"math/big"
+	"testing"
+)
+
+func squarePolygon(r, c, size uint) *Polygon {
+	return &Polygon{Outer: ringOf([2]uint{r, c}, [2]uint{r + size, c}, [2]uint{r + size, c + size}, [2]uint{r, c + size}, [2]uint{r, c})}
+}
+
+func totalArea2(polygons []*ClipPolygon) *big.Rat {
+	area := new(big.Rat)
+	for _, p := range polygons {
+		area.Add(area, p.Area2())
+	}
+	return area
+}
+
+func sameArea2(area *big.Rat, expected int64) bool {
+	return area.Cmp(big.NewRat(expected, 1)) == 0
+}
+
+// On the grid and valid once brought back to it
+func validClip(t *testing.T, name string, polygons []*ClipPolygon) {
+	for _, cp := range polygons {
+		p, ok := cp.ToPolygon(100)
+		if !ok {
+			t.Errorf("%s: expected grid vertexes, received %v", name, cp.Outer)
+			continue
+		}
+		if violations := p.Validate(); len(violations) != 0 {
+			t.Errorf("%s: invalid result %v", name, violations)
+		}
+	}
+}
+
+func TestClipSquares(t *testing.T) {
+	a, b := squarePolygon(0, 0, 10), squarePolygon(5, 5, 10)
+	for _, expected := range []struct {
+		name     string
+		op       ClipOp
+		polygons int
+		area     int64
+	}{
+		{"union", ClipUnion, 1, 175},
+		{"intersection", ClipIntersection, 1, 25},
+		{"difference", ClipDifference, 1, 75},
+		{"xor", ClipXor, 2, 150},
+	} {
+		result := ClipPolygons(expected.op, []*Polygon{a}, []*Polygon{b})
+		if len(result) != expected.polygons || !sameArea2(totalArea2(result), 2*expected.area) {
+			t.Errorf("%s: expected %d polygons of area %d, received %d of twice %v", expected.name, expected.polygons, expected.area, len(result), totalArea2(result))
+		}
+		validClip(t, expected.name, result)
+	}
+}
+
+func TestClipHolesAndSharedEdges(t *testing.T) {
+	frame := squarePolygon(0, 0, 30)
+	frame.Holes = []LinearRing{squarePolygon(10, 10, 10).Outer}
+	frame.Normalize()
+
+	// a square exactly filling the hole turns the frame solid
+	filled := frame.Union(squarePolygon(10, 10, 10))
+	if len(filled) != 1 || len(filled[0].Holes) != 0 || !sameArea2(filled[0].Area2(), 2*900) {
+		t.Errorf("Expected one solid 30 x 30, received %v", filled)
+	}
+
+	// sharing an edge with the outside merges into one
+	merged := frame.Union(squarePolygon(0, 30, 30))
+	if len(merged) != 1 || len(merged[0].Holes) != 1 || !sameArea2(merged[0].Area2(), 2*(1800-100)) {
+		t.Errorf("Expected one 30 x 60 with a hole, received %v", merged)
+	}
+	validClip(t, "merged", merged)
+
+	// cutting through the hole
+	cut := frame.Difference(squarePolygon(0, 15, 30))
+	if len(cut) != 1 || !sameArea2(totalArea2(cut), 2*(450-50)) {
+		t.Errorf("Expected one polygon of area 400, received %d of twice %v", len(cut), totalArea2(cut))
+	}
+	validClip(t, "cut", cut)
+	island := squarePolygon(12, 12, 2).Intersection(frame)
+	if len(island) != 0 {
+		t.Errorf("Expected nothing from a square within the hole, received %v", island)
+	}
+}
+
+func TestClipMatchesRaster(t *testing.T) {
+	a, b := NewMatrixBitSet(40, 40), NewMatrixBitSet(40, 40)
+	a.Fill(2, 2, 20, 30)
+	a.Drain(5, 5, 5, 5)
+	b.Fill(10, 0, 25, 12)
+	b.Fill(0, 25, 5, 5)
+	pa, _ := a.ExtractCrackPolygons()
+	pb, _ := b.ExtractCrackPolygons()
+	union, intersection := int64(0), int64(0)
+	for i := uint(0); i < 40*40; i++ {
+		if a.test(i) || b.test(i) {
+			union++
+		}
+		if a.test(i) && b.test(i) {
+			intersection++
+		}
+	}
+	for _, expected := range []struct {
+		name string
+		op   ClipOp
+		area int64
+	}{
+		{"union", ClipUnion, union},
+		{"intersection", ClipIntersection, intersection},
+		{"xor", ClipXor, union - intersection},
+	} {
+		result := ClipPolygons(expected.op, pa, pb)
+		if !sameArea2(totalArea2(result), 2*expected.area) {
+			t.Errorf("%s: expected area %d, received twice %v", expected.name, expected.area, totalArea2(result))
+		}
+		validClip(t, expected.name, result)
+	}
+}
+
+func TestClipBetweenGridPoints(t *testing.T) {
+	// two triangles crossing in a diamond with two vertexes off the grid
+	a := &Polygon{Outer: ringOf([2]uint{0, 0}, [2]uint{1, 1}, [2]uint{0, 2}, [2]uint{0, 0})}
+	b := &Polygon{Outer: ringOf([2]uint{1, 0}, [2]uint{1, 2}, [2]uint{0, 1}, [2]uint{1, 0})}
+	diamond := a.Intersection(b)
+	if len(diamond) != 1 || len(diamond[0].Outer) != 5 || !sameArea2(diamond[0].Outer.Area2(), 1) {
+		t.Fatalf("Expected a counter clockwise diamond of area 1/2, received %v", diamond)
+	}
+	found := false
+	for _, pt := range diamond[0].Outer {
+		found = found || pt.equal(ClipPoint{big.NewRat(1, 2), big.NewRat(1, 2)})
+	}
+	if !found {
+		t.Errorf("Expected a vertex at [0.5, 0.5], received %v", diamond[0].Outer)
+	}
+	if _, ok := diamond[0].ToPolygon(100); ok {
+		t.Error("Expected the diamond not to fit the grid")
+	}
+	if union := a.Union(b); len(union) != 1 || !sameArea2(totalArea2(union), 3) {
+		t.Errorf("Expected one polygon of area 3/2, received %v", union)
+	}
+	if xor := a.Xor(b); !sameArea2(totalArea2(xor), 2) {
+		t.Errorf("Expected xor area 1, received twice %v", totalArea2(xor))
+	}
+}
+
+// Far apart grid points whose edges cross at fractions a float64
+// can't hold, the areas still add up exactly
+func TestClipExactCrossings(t *testing.T) {
+	const far = 1 << 40
+	ring := func(points ...[2]uint) (LinearRing, *big.Rat) {
+		lr, cr := make(LinearRing, 0, len(points)), make(ClipRing, 0, len(points))
+		for _, p := range points {
+			lr = append(lr, NewMatrixPos(p[0], p[1], far+8))
+			cr = append(cr, gridClipPoint(int64(p[0]), int64(p[1])))
+		}
+		return lr, new(big.Rat).Abs(cr.Area2())
+	}
+	outerA, areaA := ring([2]uint{0, 0}, [2]uint{far + 1, far}, [2]uint{0, far - 3}, [2]uint{0, 0})
+	outerB, areaB := ring([2]uint{1, 0}, [2]uint{3, far + 7}, [2]uint{far - 5, 2}, [2]uint{1, 0})
+	a, b := &Polygon{Outer: outerA}, &Polygon{Outer: outerB}
+	union, intersection := totalArea2(a.Union(b)), totalArea2(a.Intersection(b))
+	if sum := new(big.Rat).Add(union, intersection); sum.Cmp(new(big.Rat).Add(areaA, areaB)) != 0 {
+		t.Errorf("Expected union and intersection to add up to %v, received %v + %v", new(big.Rat).Add(areaA, areaB), union, intersection)
+	}
+	xor, difference := totalArea2(a.Xor(b)), totalArea2(a.Difference(b))
+	if xor.Cmp(new(big.Rat).Sub(union, intersection)) != 0 || difference.Cmp(new(big.Rat).Sub(areaA, intersection)) != 0 {
+		t.Errorf("Unexpected xor %v and difference %v", xor, difference)
+	}
+	if intersection.IsInt() {
+		t.Errorf("Expected the crossings to leave a fractional area, received %v", intersection)
+	}
+}
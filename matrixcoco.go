@@ -0,0 +1,189 @@
+package matrixbitset
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Run length masks as the COCO dataset and pycocotools write them.
+// Runs go down each col in turn, column-major, starting with a clear run,
+// so a mask whose first pixel is set starts with a 0 count
+
+type COCORLE struct {
+	Height, Width uint
+	Counts        []uint
+}
+
+func (m *MatrixBitSet) EncodeCOCORLE() *COCORLE {
+	// the transpose's rows are our cols
+	return &COCORLE{Height: m.R, Width: m.C, Counts: m.Transpose().runs()}
+}
+
+// Errors when the counts don't cover Height x Width exactly,
+// which is checked before the mask is allocated
+func DecodeCOCORLE(rle *COCORLE) (*MatrixBitSet, error) {
+	if rle.Height != 0 && rle.Width != 0 {
+		if err := checkDimensions(rle.Width, rle.Height); err != nil {
+			return nil, fmt.Errorf("coco rle: %v", err)
+		}
+	}
+	// the transpose's rows are our cols
+	if err := checkRuns(rle.Counts, rle.Width, rle.Height); err != nil {
+		return nil, fmt.Errorf("coco rle: %v", err)
+	}
+	transposed := NewMatrixBitSet(rle.Height, rle.Width)
+	if err := transposed.setRuns(rle.Counts); err != nil {
+		return nil, fmt.Errorf("coco rle: %v", err)
+	}
+	return transposed.Transpose(), nil
+}
+
+// The compact string form of the counts, pycocotools rleToString.
+// Each count past the second is stored as its difference from the count
+// two before, then written 5 bits at a time, low first, as characters
+// from '0', 0x20 set on all but the last and 0x10 the sign
+func (rle *COCORLE) String() string {
+	s := make([]byte, 0, len(rle.Counts)*2)
+	for i, count := range rle.Counts {
+		x := int64(count)
+		if i > 2 {
+			x -= int64(rle.Counts[i-2])
+		}
+		for more := true; more; {
+			c := byte(x & 0x1f)
+			x >>= 5
+			if c&0x10 != 0 {
+				more = x != -1
+			} else {
+				more = x != 0
+			}
+			if more {
+				c |= 0x20
+			}
+			s = append(s, c+'0')
+		}
+	}
+	return string(s)
+}
+
+// The inverse of String, pycocotools rleFrString
+func ParseCOCORLE(height, width uint, s string) (*COCORLE, error) {
+	counts := make([]uint, 0)
+	for p := 0; p < len(s); {
+		var x int64
+		k := uint(0)
+		for more := true; more; k++ {
+			if p == len(s) {
+				return nil, fmt.Errorf("coco rle: %q ends mid count", s)
+			}
+			c := int64(s[p]) - '0'
+			if c < 0 || c > 0x3f {
+				return nil, fmt.Errorf("coco rle: unexpected %q at %d", s[p], p)
+			}
+			p++
+			x |= (c & 0x1f) << (5 * k)
+			more = c&0x20 != 0
+			if !more && c&0x10 != 0 {
+				x |= -1 << (5 * (k + 1))
+			}
+		}
+		if len(counts) > 2 {
+			x += int64(counts[len(counts)-2])
+		}
+		if x < 0 {
+			return nil, fmt.Errorf("coco rle: negative count in %q", s)
+		}
+		counts = append(counts, uint(x))
+	}
+	return &COCORLE{Height: height, Width: width, Counts: counts}, nil
+}
+
+// Set pixels, the sum of the odd counts
+func (rle *COCORLE) Area() uint {
+	area := uint(0)
+	for i := 1; i < len(rle.Counts); i += 2 {
+		area += rle.Counts[i]
+	}
+	return area
+}
+
+// COCO's [x, y, width, height] around the set pixels, x being the col
+// false when there are none
+func (rle *COCORLE) BBox() ([4]uint, bool) {
+	if rle.Height == 0 {
+		return [4]uint{}, false
+	}
+	found := false
+	var minX, minY, maxX, maxY uint
+	at := uint(0)
+	for i, count := range rle.Counts {
+		start, end := at, at+count
+		at = end
+		if i%2 == 0 || count == 0 {
+			continue
+		}
+		x0, x1 := start/rle.Height, (end-1)/rle.Height
+		y0, y1 := start%rle.Height, (end-1)%rle.Height
+		if x0 != x1 {
+			// the run wraps into the next col, so spans every row
+			y0, y1 = 0, rle.Height-1
+		}
+		if !found {
+			minX, minY, maxX, maxY = x0, y0, x1, y1
+			found = true
+			continue
+		}
+		minX, maxX = minUint(minX, x0), maxUint(maxX, x1)
+		minY, maxY = minUint(minY, y0), maxUint(maxY, y1)
+	}
+	if !found {
+		return [4]uint{}, false
+	}
+	return [4]uint{minX, minY, maxX - minX + 1, maxY - minY + 1}, true
+}
+
+type cocoJSON struct {
+	Size   [2]uint         `json:"size"`
+	Counts json.RawMessage `json:"counts"`
+}
+
+// {"size": [height, width], "counts": [...]}
+func (rle *COCORLE) MarshalJSON() ([]byte, error) {
+	counts, err := json.Marshal(rle.Counts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cocoJSON{Size: [2]uint{rle.Height, rle.Width}, Counts: counts})
+}
+
+// {"size": [height, width], "counts": "..."} with the compact counts
+func (rle *COCORLE) MarshalCompactJSON() ([]byte, error) {
+	counts, err := json.Marshal(rle.String())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cocoJSON{Size: [2]uint{rle.Height, rle.Width}, Counts: counts})
+}
+
+// Takes counts as either an array or the compact string
+func (rle *COCORLE) UnmarshalJSON(data []byte) error {
+	var cj cocoJSON
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return fmt.Errorf("coco rle: %v", err)
+	}
+	var compact string
+	if err := json.Unmarshal(cj.Counts, &compact); err == nil {
+		parsed, err := ParseCOCORLE(cj.Size[0], cj.Size[1], compact)
+		if err != nil {
+			return err
+		}
+		*rle = *parsed
+		return nil
+	}
+	var counts []uint
+	if err := json.Unmarshal(cj.Counts, &counts); err != nil {
+		return fmt.Errorf("coco rle: counts are neither a string nor an array: %v", err)
+	}
+	*rle = COCORLE{Height: cj.Size[0], Width: cj.Size[1], Counts: counts}
+	return nil
+}
@@ -0,0 +1,117 @@
+package matrixbitset
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeCOCORLE(t *testing.T) {
+	m, _ := ParseASCII(`
+		#..
+		##.
+		...
+	`)
+	// down col 0, then col 1, then col 2
+	rle := m.EncodeCOCORLE()
+	if expected := []uint{0, 2, 2, 1, 4}; !reflect.DeepEqual(rle.Counts, expected) {
+		t.Errorf("Expected %v, received %v", expected, rle.Counts)
+	}
+	if rle.Area() != 3 {
+		t.Errorf("Expected area 3, received %d", rle.Area())
+	}
+	if bbox, ok := rle.BBox(); !ok || bbox != [4]uint{0, 0, 2, 2} {
+		t.Errorf("Expected bbox [0 0 2 2], received %v", bbox)
+	}
+	back, err := DecodeCOCORLE(rle)
+	if err != nil || !sameBits(back, m) {
+		t.Errorf("Unexpected decode %v", err)
+	}
+	if _, err := DecodeCOCORLE(&COCORLE{Height: 3, Width: 3, Counts: []uint{0, 2}}); err == nil {
+		t.Error("Expected an error for counts short of the mask")
+	}
+	// Height x Width wraps to 0
+	if _, err := DecodeCOCORLE(&COCORLE{Height: 1 << 32, Width: 1 << 32}); err == nil {
+		t.Error("Expected an error for 2^32 x 2^32 without counts")
+	}
+	var huge COCORLE
+	if err := json.Unmarshal([]byte(`{"size":[67108864,67108864],"counts":[]}`), &huge); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeCOCORLE(&huge); err == nil {
+		t.Error("Expected an error for a mask too big to allocate")
+	}
+}
+
+func TestCOCORLEString(t *testing.T) {
+	for _, tc := range []struct {
+		counts []uint
+		s      string
+	}{
+		{[]uint{0, 1}, "01"},
+		{[]uint{3, 4, 5, 6}, "3452"},
+		{[]uint{1, 10, 1, 2}, "1:1H"},
+		{[]uint{100000}, "PeQ3"},
+	} {
+		rle := &COCORLE{Height: 1, Width: 1, Counts: tc.counts}
+		if received := rle.String(); received != tc.s {
+			t.Errorf("%v: expected %q, received %q", tc.counts, tc.s, received)
+		}
+		parsed, err := ParseCOCORLE(1, 1, tc.s)
+		if err != nil || !reflect.DeepEqual(parsed.Counts, tc.counts) {
+			t.Errorf("%q: expected %v, received %v, %v", tc.s, tc.counts, parsed, err)
+		}
+	}
+	for _, bad := range []string{"1:1h", "1 2", "o"} {
+		if _, err := ParseCOCORLE(1, 1, bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestCOCORLEAgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(19))
+	for trial := 0; trial < 40; trial++ {
+		m := randomMatrix(rnd, uint(1+rnd.Intn(40)), uint(1+rnd.Intn(40)), rnd.Float64()*0.5)
+		rle := m.EncodeCOCORLE()
+		if rle.Area() != m.Count() {
+			t.Errorf("Expected area %d, received %d", m.Count(), rle.Area())
+		}
+		bbox, ok := rle.BBox()
+		if expected, found := bruteBBox(m); ok != found || bbox != expected {
+			t.Errorf("Expected bbox %v, received %v\n%s", expected, bbox, m.FormatASCII())
+		}
+
+		compact, _ := rle.MarshalCompactJSON()
+		plain, _ := rle.MarshalJSON()
+		for _, data := range [][]byte{compact, plain} {
+			var back COCORLE
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := DecodeCOCORLE(&back)
+			if err != nil || !sameBits(decoded, m) {
+				t.Errorf("Round trip through %s differs, %v", data, err)
+			}
+		}
+	}
+}
+
+// [x, y, width, height] by testing every pixel
+func bruteBBox(m *MatrixBitSet) ([4]uint, bool) {
+	found := false
+	minR, minC, maxR, maxC := m.R, m.C, uint(0), uint(0)
+	for r := uint(0); r < m.R; r++ {
+		for c := uint(0); c < m.C; c++ {
+			if m.Test(r, c) {
+				found = true
+				minR, minC, maxR, maxC = minUint(minR, r), minUint(minC, c), maxUint(maxR, r), maxUint(maxC, c)
+			}
+		}
+	}
+	if !found {
+		return [4]uint{}, false
+	}
+	return [4]uint{minC, minR, maxC - minC + 1, maxR - minR + 1}, true
+}
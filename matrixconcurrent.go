@@ -0,0 +1,228 @@
+package matrixbitset
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// A MatrixBitSet that is safe for many goroutines to paint into
+// Single bit ops are lock free CAS loops on the uint64 words,
+// bulk ops (Fill, Drain, Invert, Count, Snapshot) additionally take
+// the stripe locks covering their words so they appear atomic to each other
+type ConcurrentMatrixBitSet struct {
+	m           *MatrixBitSet
+	stripes     []sync.RWMutex
+	stripeWords int
+}
+
+func NewConcurrentMatrixBitSet(w, h uint, stripes int) *ConcurrentMatrixBitSet {
+	return NewConcurrentFrom(NewMatrixBitSet(w, h), stripes)
+}
+
+// Takes ownership of m, it must not be used directly afterwards
+func NewConcurrentFrom(m *MatrixBitSet, stripes int) *ConcurrentMatrixBitSet {
+	if stripes < 1 {
+		stripes = 1
+	}
+	if stripes > len(m.B) && len(m.B) > 0 {
+		stripes = len(m.B)
+	}
+	// Round up so every word lands in a stripe
+	stripeWords := (len(m.B) + stripes - 1) / stripes
+	if stripeWords == 0 {
+		stripeWords = 1
+	}
+	return &ConcurrentMatrixBitSet{m: m, stripes: make([]sync.RWMutex, stripes), stripeWords: stripeWords}
+}
+
+func (cm *ConcurrentMatrixBitSet) Rows() uint {
+	return cm.m.R
+}
+
+func (cm *ConcurrentMatrixBitSet) Cols() uint {
+	return cm.m.C
+}
+
+func (cm *ConcurrentMatrixBitSet) Test(r, c uint) bool {
+	cm.m.panicPastMatrix(r, c)
+	return cm.test(cm.m.index(r, c))
+}
+
+func (cm *ConcurrentMatrixBitSet) TestN(i uint) bool {
+	cm.m.panicOverSized(i)
+	return cm.test(i)
+}
+
+// Returns true if this call turned the bit on
+func (cm *ConcurrentMatrixBitSet) Set(r, c uint) bool {
+	cm.m.panicPastMatrix(r, c)
+	return cm.set(cm.m.index(r, c))
+}
+
+// Returns true if this call turned the bit on
+func (cm *ConcurrentMatrixBitSet) SetN(i uint) bool {
+	cm.m.panicOverSized(i)
+	return cm.set(i)
+}
+
+// Returns true if this call turned the bit off
+func (cm *ConcurrentMatrixBitSet) Clear(r, c uint) bool {
+	cm.m.panicPastMatrix(r, c)
+	return cm.clear(cm.m.index(r, c))
+}
+
+// Returns true if this call turned the bit off
+func (cm *ConcurrentMatrixBitSet) ClearN(i uint) bool {
+	cm.m.panicOverSized(i)
+	return cm.clear(i)
+}
+
+func (cm *ConcurrentMatrixBitSet) Fill(r, c, dr, dc uint) *ConcurrentMatrixBitSet {
+	cm.m.panicPastMatrix(r, c)
+	cm.m.panicPastMatrix(r+dr, c+dc)
+	cm.eachRowRange(r, c, dr, dc, func(x int, mask uint64) {
+		cm.or(x, mask)
+	})
+	return cm
+}
+
+func (cm *ConcurrentMatrixBitSet) Drain(r, c, dr, dc uint) *ConcurrentMatrixBitSet {
+	cm.m.panicPastMatrix(r, c)
+	cm.m.panicPastMatrix(r+dr, c+dc)
+	cm.eachRowRange(r, c, dr, dc, func(x int, mask uint64) {
+		cm.andNot(x, mask)
+	})
+	return cm
+}
+
+// Flip every bit in place, the padding past the last bit stays clear
+func (cm *ConcurrentMatrixBitSet) Invert() *ConcurrentMatrixBitSet {
+	cm.lockWords(0, len(cm.m.B)-1)
+	defer cm.unlockWords(0, len(cm.m.B)-1)
+	total := cm.m.R * cm.m.C
+	for x := range cm.m.B {
+		for {
+			w := atomic.LoadUint64(&cm.m.B[x])
+			if atomic.CompareAndSwapUint64(&cm.m.B[x], w, ^w&rowMask(total, x)) {
+				break
+			}
+		}
+	}
+	return cm
+}
+
+func (cm *ConcurrentMatrixBitSet) Count() uint {
+	cm.rlockWords(0, len(cm.m.B)-1)
+	defer cm.runlockWords(0, len(cm.m.B)-1)
+	var cnt int
+	for x := range cm.m.B {
+		cnt += bits.OnesCount64(atomic.LoadUint64(&cm.m.B[x]))
+	}
+	return uint(cnt)
+}
+
+// Copies the current state into a plain MatrixBitSet
+// consistent with respect to bulk ops
+func (cm *ConcurrentMatrixBitSet) Snapshot() *MatrixBitSet {
+	cm.rlockWords(0, len(cm.m.B)-1)
+	defer cm.runlockWords(0, len(cm.m.B)-1)
+	result := NewMatrixBitSet(cm.m.C, cm.m.R)
+	for x := range cm.m.B {
+		result.B[x] = atomic.LoadUint64(&cm.m.B[x])
+	}
+	return result
+}
+
+func (cm *ConcurrentMatrixBitSet) test(i uint) bool {
+	return atomic.LoadUint64(&cm.m.B[i>>log2WordSize])&(1<<(i&(wordSize-1))) != 0
+}
+
+func (cm *ConcurrentMatrixBitSet) set(i uint) bool {
+	bit := uint64(1) << (i & (wordSize - 1))
+	return cm.or(int(i>>log2WordSize), bit)&bit == 0
+}
+
+func (cm *ConcurrentMatrixBitSet) clear(i uint) bool {
+	bit := uint64(1) << (i & (wordSize - 1))
+	return cm.andNot(int(i>>log2WordSize), bit)&bit != 0
+}
+
+// CAS loops, both return the word as it was before
+func (cm *ConcurrentMatrixBitSet) or(x int, mask uint64) uint64 {
+	for {
+		w := atomic.LoadUint64(&cm.m.B[x])
+		if w&mask == mask || atomic.CompareAndSwapUint64(&cm.m.B[x], w, w|mask) {
+			return w
+		}
+	}
+}
+
+func (cm *ConcurrentMatrixBitSet) andNot(x int, mask uint64) uint64 {
+	for {
+		w := atomic.LoadUint64(&cm.m.B[x])
+		if w&mask == 0 || atomic.CompareAndSwapUint64(&cm.m.B[x], w, w&^mask) {
+			return w
+		}
+	}
+}
+
+// Walks each row of the box as word masks while holding
+// the stripes covering the whole box
+func (cm *ConcurrentMatrixBitSet) eachRowRange(r, c, dr, dc uint, fn func(x int, mask uint64)) {
+	if dr == 0 || dc == 0 {
+		return
+	}
+	first := int(cm.m.index(r, c) >> log2WordSize)
+	last := int(cm.m.index(r+dr-1, c+dc-1) >> log2WordSize)
+	cm.lockWords(first, last)
+	defer cm.unlockWords(first, last)
+	for row := r; row < r+dr; row++ {
+		lo, hi := cm.m.index(row, c), cm.m.index(row, c+dc)
+		for lo < hi {
+			x := lo >> log2WordSize
+			end := (x + 1) << log2WordSize
+			if end > hi {
+				end = hi
+			}
+			mask := allBits << (lo & (wordSize - 1))
+			if end&(wordSize-1) != 0 {
+				mask &= allBits >> (wordSize - (end & (wordSize - 1)))
+			}
+			fn(int(x), mask)
+			lo = end
+		}
+	}
+}
+
+// Stripes are always taken in ascending order so bulk ops can't deadlock
+func (cm *ConcurrentMatrixBitSet) lockWords(first, last int) {
+	for s := cm.stripeOf(first); s <= cm.stripeOf(last); s++ {
+		cm.stripes[s].Lock()
+	}
+}
+
+func (cm *ConcurrentMatrixBitSet) unlockWords(first, last int) {
+	for s := cm.stripeOf(last); s >= cm.stripeOf(first); s-- {
+		cm.stripes[s].Unlock()
+	}
+}
+
+func (cm *ConcurrentMatrixBitSet) rlockWords(first, last int) {
+	for s := cm.stripeOf(first); s <= cm.stripeOf(last); s++ {
+		cm.stripes[s].RLock()
+	}
+}
+
+func (cm *ConcurrentMatrixBitSet) runlockWords(first, last int) {
+	for s := cm.stripeOf(last); s >= cm.stripeOf(first); s-- {
+		cm.stripes[s].RUnlock()
+	}
+}
+
+func (cm *ConcurrentMatrixBitSet) stripeOf(x int) int {
+	if x < 0 {
+		return 0
+	}
+	return x / cm.stripeWords
+}
@@ -0,0 +1,95 @@
+package matrixbitset
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSet(t *testing.T) {
+	cm := NewConcurrentMatrixBitSet(100, 100, 8)
+	var wg sync.WaitGroup
+	// interleave the goroutines so they all fight over the same words
+	for g := uint(0); g < 8; g++ {
+		wg.Add(1)
+		go func(g uint) {
+			defer wg.Done()
+			for i := g; i < 100*100; i += 8 {
+				cm.SetN(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if cm.Count() != 100*100 {
+		t.Errorf("Expected %d bits on, received %d", 100*100, cm.Count())
+	}
+	for g := uint(0); g < 4; g++ {
+		wg.Add(1)
+		go func(g uint) {
+			defer wg.Done()
+			for i := g; i < 100*100; i += 4 {
+				if !cm.ClearN(i) {
+					t.Errorf("Expected ClearN(%d) to turn the bit off", i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if cm.Count() != 0 {
+		t.Errorf("Expected 0 bits on, received %d", cm.Count())
+	}
+}
+
+func TestConcurrentFill(t *testing.T) {
+	const w, h = 257, 131
+	cm := NewConcurrentMatrixBitSet(w, h, 4)
+	expected := NewMatrixBitSet(w, h)
+	var wg sync.WaitGroup
+	for g := uint(0); g < 6; g++ {
+		r, c := g*20, g*30+3
+		expected.Fill(r, c, 17, 71)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cm.Fill(r, c, 17, 71)
+		}()
+		wg.Add(1)
+		go func(g uint) {
+			defer wg.Done()
+			cm.Set(h-1, g)
+		}(g)
+	}
+	wg.Wait()
+	for g := uint(0); g < 6; g++ {
+		expected.Set(h-1, g)
+	}
+	snap := cm.Snapshot()
+	for i := range expected.B {
+		if snap.B[i] != expected.B[i] {
+			t.Fatalf("Word %d differs, expected %s received %s", i, expected.FormatWord(expected.B[i]), snap.FormatWord(snap.B[i]))
+		}
+	}
+	cm.Drain(0, 0, 120, w-1)
+	if cm.Count() != 6 {
+		t.Errorf("Expected 6 bits on after Drain, received %d", cm.Count())
+	}
+}
+
+func TestConcurrentInvert(t *testing.T) {
+	// 3 x 5 leaves 49 bits of padding in the only word
+	cm := NewConcurrentMatrixBitSet(5, 3, 2)
+	cm.Set(1, 2)
+	cm.Invert()
+	if cm.Count() != 14 {
+		t.Errorf("Expected 14 bits on after Invert, received %d", cm.Count())
+	}
+	snap := cm.Snapshot()
+	if snap.Test(1, 2) || !snap.Test(2, 4) {
+		t.Errorf("Expected only [1, 2] clear, received\n%s", snap.FormatASCII())
+	}
+	if tr := snap.Transpose(); tr.Count() != 14 {
+		t.Errorf("Expected 14 bits on after Transpose, received %d", tr.Count())
+	}
+	if plain := NewMatrixBitSet(5, 3).Set(1, 2).Invert(); plain.B[0] != snap.B[0] {
+		t.Errorf("Expected Invert to match, received %s and %s", plain.FormatWord(plain.B[0]), snap.FormatWord(snap.B[0]))
+	}
+}
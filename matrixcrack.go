@@ -0,0 +1,158 @@
+package matrixbitset
+
+// Crack following walks the edges between set and clear pixels
+// through the pixel corners of a VertexSpace, rather than through
+// pixel centers like TraceShell. Each ring keeps its set pixels on
+// its left, so outer rings come out counter clockwise and holes
+// clockwise (as displayed) and Polygon.Area2() / 2 is exactly the
+// number of bits set in it. Neighbouring regions share their edges.
+
+const (
+	crackDown = iota
+	crackRight
+	crackUp
+	crackLeft
+)
+
+// row, col deltas in crack direction order, each is a left turn from the last
+var crackSteps = [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// Extracts watertight polygons whose vertexes are pixel corners
+// strided by C+1. Pixels only touching at a corner are treated as
+// separate polygons (4 connectivity)
+func (m *MatrixBitSet) ExtractCrackPolygons() ([]*Polygon, bool) {
+	return m.VertexSpace().Contours()
+}
+
+func (vs *VertexSpace) Contours() ([]*Polygon, bool) {
+	edges := vs.crackEdges()
+	outers := make([]LinearRing, 0, 128)
+	holes := make([]LinearRing, 0, 128)
+	// every ring has at least one down edge, so those are enough to find them all
+	for start, ok := edges[crackDown].nextSet(0); ok; start, ok = edges[crackDown].nextSet(start) {
+		ring := vs.traceCrack(edges, start)
+		if ring.Area2() > 0 {
+			outers = append(outers, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	polygons := make([]*Polygon, len(outers))
+	for i, outer := range outers {
+		polygons[i] = &Polygon{Outer: outer, Holes: make([]LinearRing, 0)}
+	}
+	for _, hole := range holes {
+		// the set pixel left of the first edge belongs to the owning polygon
+		r, c := crackLeftPixel(hole[0], hole[1])
+		owner := -1
+		for i, outer := range outers {
+			if ringContainsCenter(outer, r, c) && (owner < 0 || outer.Area2() < outers[owner].Area2()) {
+				owner = i
+			}
+		}
+		if owner < 0 {
+			return nil, false
+		}
+		polygons[owner].Holes = append(polygons[owner].Holes, hole)
+	}
+	return polygons, true
+}
+
+// One bitset of outgoing edges per direction, indexed by corner
+func (vs *VertexSpace) crackEdges() [4]*MatrixBitSet {
+	m := vs.mb
+	cols, rows := vs.corners()
+	var edges [4]*MatrixBitSet
+	for d := range edges {
+		edges[d] = NewMatrixBitSet(cols, rows)
+	}
+	corner := func(r, c uint) uint {
+		return r*cols + c
+	}
+	for i, e := m.nextSet(0); e && i < m.R*m.C; i, e = m.nextSet(i + 1) {
+		pos := m.NewPos(i)
+		r, c := pos.Both()
+		if m.testPos(pos.Left()) == 0 {
+			edges[crackDown].set(corner(r, c))
+		}
+		if m.testPos(pos.Down(m.R)) == 0 {
+			edges[crackRight].set(corner(r+1, c))
+		}
+		if m.testPos(pos.Right(m.C)) == 0 {
+			edges[crackUp].set(corner(r+1, c+1))
+		}
+		if m.testPos(pos.Up()) == 0 {
+			edges[crackLeft].set(corner(r, c+1))
+		}
+	}
+	return edges
+}
+
+// Follows edges from the down edge at start, clearing them as it goes
+// At a saddle (two outgoing edges) it turns left, keeping
+// diagonal only neighbours apart
+func (vs *VertexSpace) traceCrack(edges [4]*MatrixBitSet, start uint) LinearRing {
+	ring := make(LinearRing, 0, 128)
+	cols, _ := vs.corners()
+	v, d := start, crackDown
+	for {
+		edges[d].clear(v)
+		r := int(v/cols) + crackSteps[d][0]
+		c := int(v%cols) + crackSteps[d][1]
+		v = uint(r)*cols + uint(c)
+
+		next := -1
+		for _, turn := range []int{1, 0, 3} {
+			candidate := (d + turn) % 4
+			if v == start && candidate == crackDown {
+				// back on the edge we started with
+				break
+			}
+			if edges[candidate].test(v) {
+				next = candidate
+				break
+			}
+		}
+		if next != d {
+			ring = append(ring, MatrixPos{r: uint(r), c: uint(c), stride: cols})
+		}
+		if next < 0 {
+			break
+		}
+		d = next
+	}
+	// the loop ends on start, move it to the front so the ring opens and closes there
+	ring = append(LinearRing{ring[len(ring)-1]}, ring...)
+	return ring
+}
+
+// The pixel on the left of the directed edge from -> to
+func crackLeftPixel(from, to MatrixPos) (uint, uint) {
+	switch {
+	case to.r > from.r:
+		return from.r, from.c
+	case to.c > from.c:
+		return from.r - 1, from.c
+	case to.r < from.r:
+		return to.r, to.c - 1
+	default:
+		return to.r, to.c
+	}
+}
+
+// Is the center of pixel [r, c] within a ring of corners?
+// Centers never sit on a corner line, so doubling everything
+// keeps the ray casting exact
+func ringContainsCenter(ring LinearRing, r, c uint) bool {
+	y, x := int(2*r+1), int(2*c+1)
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		yi, xi := 2*ring[i].Row_i(), 2*ring[i].Col_i()
+		yj, xj := 2*ring[j].Row_i(), 2*ring[j].Col_i()
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
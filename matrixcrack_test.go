@@ -0,0 +1,112 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func TestCrackAreaMatchesCount(t *testing.T) {
+	// a ring holding an island with a hole, two pixels touching
+	// only at a corner and a one pixel wide spur
+	m, _ := ParseASCII(`
+		..........#...
+		.#######...#..
+		.#.....#......
+		.#.###.#......
+		.#.#.#.#..#...
+		.#.###.#..#...
+		.#.....#..#...
+		.#######..####
+		..............
+	`)
+	polygons, ok := m.ExtractCrackPolygons()
+	if !ok {
+		t.Fatal("ExtractCrackPolygons failed")
+	}
+	if len(polygons) != 5 {
+		t.Errorf("Expected 5 polygons, received %d", len(polygons))
+	}
+	area2 := 0
+	holes := 0
+	for _, p := range polygons {
+		if p.Outer[0] != p.Outer[len(p.Outer)-1] {
+			t.Errorf("Ring %v is not closed", p.Outer)
+		}
+		if p.Outer.Area2() <= 0 {
+			t.Errorf("Expected a counter clockwise outer ring, received %d", p.Outer.Area2())
+		}
+		area2 += p.Area2()
+		holes += len(p.Holes)
+	}
+	if area2 != 2*int(m.Count()) {
+		t.Errorf("Expected area %d, received %d", m.Count(), area2/2)
+	}
+	if holes != 2 {
+		t.Errorf("Expected 2 holes, received %d", holes)
+	}
+}
+
+// Unit edges along the rings, ignoring the edges on the matrix border
+func crackInteriorEdges(polygons []*Polygon, m *MatrixBitSet) map[[4]int]bool {
+	edges := make(map[[4]int]bool)
+	add := func(ring LinearRing) {
+		for i := 0; i+1 < len(ring); i++ {
+			r, c := ring[i].Row_i(), ring[i].Col_i()
+			for r != ring[i+1].Row_i() || c != ring[i+1].Col_i() {
+				nr, nc := r+sign(ring[i+1].Row_i()-r), c+sign(ring[i+1].Col_i()-c)
+				onBorder := (r == nr && (r == 0 || r == int(m.R))) || (c == nc && (c == 0 || c == int(m.C)))
+				if !onBorder {
+					if nr < r || nc < c {
+						edges[[4]int{nr, nc, r, c}] = true
+					} else {
+						edges[[4]int{r, c, nr, nc}] = true
+					}
+				}
+				r, c = nr, nc
+			}
+		}
+	}
+	for _, p := range polygons {
+		add(p.Outer)
+		for _, h := range p.Holes {
+			add(h)
+		}
+	}
+	return edges
+}
+
+func sign(x int) int {
+	if x < 0 {
+		return -1
+	} else if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func TestCrackSharedEdges(t *testing.T) {
+	pixels := `
+		..........#...
+		.#######...#..
+		.#.....#......
+		.#.###.#......
+		.#.#.#.#..#...
+		.#.###.#..#...
+		.#.....#..#...
+		.#######..####
+		..............
+	`
+	m, _ := ParseASCII(pixels)
+	inverted, _ := ParseASCII(pixels)
+	inverted.Invert()
+	set, _ := m.ExtractCrackPolygons()
+	clear, _ := inverted.ExtractCrackPolygons()
+	setEdges, clearEdges := crackInteriorEdges(set, m), crackInteriorEdges(clear, m)
+	if len(setEdges) != len(clearEdges) {
+		t.Fatalf("Expected the same edges on both sides, received %d and %d", len(setEdges), len(clearEdges))
+	}
+	for e := range setEdges {
+		if !clearEdges[e] {
+			t.Errorf("Edge %v missing from the inverted polygons", e)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package matrixbitset
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned (wrapped in an *OutOfBoundsError) by the Try variants
+// instead of panicking like their counterparts do
+var ErrOutOfBounds = errors.New("exceeds matrix bounds")
+
+// Carries the offending position along with the matrix dimensions
+// Index is only meaningful when ByIndex is true, otherwise R, C are
+type OutOfBoundsError struct {
+	R, C       uint
+	Index      uint
+	ByIndex    bool
+	Rows, Cols uint
+}
+
+func (e *OutOfBoundsError) Error() string {
+	if e.ByIndex {
+		return fmt.Sprintf("[%d] exceeds matrix bounds %d", e.Index, e.Rows*e.Cols)
+	}
+	return fmt.Sprintf("[%d, %d] exceeds matrix bounds %d x %d", e.R, e.C, e.Rows, e.Cols)
+}
+
+func (e *OutOfBoundsError) Unwrap() error {
+	return ErrOutOfBounds
+}
+
+func (m *MatrixBitSet) checkPastMatrix(r, c uint) error {
+	if r >= m.R || c >= m.C {
+		return &OutOfBoundsError{R: r, C: c, Rows: m.R, Cols: m.C}
+	}
+	return nil
+}
+
+func (m *MatrixBitSet) checkOverSized(i uint) error {
+	if i >= m.R*m.C {
+		return &OutOfBoundsError{Index: i, ByIndex: true, Rows: m.R, Cols: m.C}
+	}
+	return nil
+}
+
+func (m *MatrixBitSet) TryTest(r, c uint) (bool, error) {
+	if e := m.checkPastMatrix(r, c); e != nil {
+		return false, e
+	}
+	return m.test(m.index(r, c)), nil
+}
+
+func (m *MatrixBitSet) TryTestN(i uint) (bool, error) {
+	if e := m.checkOverSized(i); e != nil {
+		return false, e
+	}
+	return m.test(i), nil
+}
+
+func (m *MatrixBitSet) TrySet(r, c uint) error {
+	if e := m.checkPastMatrix(r, c); e != nil {
+		return e
+	}
+	m.set(m.index(r, c))
+	return nil
+}
+
+func (m *MatrixBitSet) TrySetN(i uint) error {
+	if e := m.checkOverSized(i); e != nil {
+		return e
+	}
+	m.set(i)
+	return nil
+}
+
+func (m *MatrixBitSet) TryClear(r, c uint) error {
+	if e := m.checkPastMatrix(r, c); e != nil {
+		return e
+	}
+	m.clear(m.index(r, c))
+	return nil
+}
+
+func (m *MatrixBitSet) TryClearN(i uint) error {
+	if e := m.checkOverSized(i); e != nil {
+		return e
+	}
+	m.clear(i)
+	return nil
+}
+
+// Validates the same corners as Fill, nothing is set on error
+func (m *MatrixBitSet) TryFill(r, c, dr, dc uint) error {
+	if e := m.checkBox(r, c, dr, dc); e != nil {
+		return e
+	}
+	m.Fill(r, c, dr, dc)
+	return nil
+}
+
+// Validates the same corners as Drain, nothing is cleared on error
+func (m *MatrixBitSet) TryDrain(r, c, dr, dc uint) error {
+	if e := m.checkBox(r, c, dr, dc); e != nil {
+		return e
+	}
+	m.Drain(r, c, dr, dc)
+	return nil
+}
+
+func (m *MatrixBitSet) TryNextSet(i uint) (uint, bool, error) {
+	if e := m.checkOverSized(i); e != nil {
+		return 0, false, e
+	}
+	n, ok := m.nextSet(i)
+	return n, ok, nil
+}
+
+func (m *MatrixBitSet) TryPrevSet(i uint) (uint, bool, error) {
+	if e := m.checkOverSized(i); e != nil {
+		return 0, false, e
+	}
+	n, ok := m.prevSet(i)
+	return n, ok, nil
+}
+
+func (mb *MatrixBounds) TryNInside(n uint) (bool, error) {
+	if e := mb.M.checkOverSized(n); e != nil {
+		return false, e
+	}
+	return mb.NInside(n), nil
+}
+
+// Like Fill, but silently clamps the box to the matrix
+// anything entirely outside is a no-op
+func (m *MatrixBitSet) FillClipped(r, c, dr, dc uint) *MatrixBitSet {
+	lastR, lastC := m.clipBox(r, c, dr, dc)
+	for row := r; row < lastR; row++ {
+		for col := c; col < lastC; col++ {
+			m.set(m.index(row, col))
+		}
+	}
+	return m
+}
+
+// Like Drain, but silently clamps the box to the matrix
+// anything entirely outside is a no-op
+func (m *MatrixBitSet) DrainClipped(r, c, dr, dc uint) *MatrixBitSet {
+	lastR, lastC := m.clipBox(r, c, dr, dc)
+	for row := r; row < lastR; row++ {
+		for col := c; col < lastC; col++ {
+			m.clear(m.index(row, col))
+		}
+	}
+	return m
+}
+
+func (m *MatrixBitSet) checkBox(r, c, dr, dc uint) error {
+	if e := m.checkPastMatrix(r, c); e != nil {
+		return e
+	}
+	if r+dr < r || c+dc < c {
+		// wrapped around, report the unwrapped start as the culprit
+		return &OutOfBoundsError{R: r, C: c, Rows: m.R, Cols: m.C}
+	}
+	return m.checkPastMatrix(r+dr, c+dc)
+}
+
+// Returns the exclusive row, col ends of the box clamped to the matrix
+func (m *MatrixBitSet) clipBox(r, c, dr, dc uint) (uint, uint) {
+	lastR, lastC := r+dr, c+dc
+	if lastR < r || lastR > m.R {
+		lastR = m.R
+	}
+	if lastC < c || lastC > m.C {
+		lastC = m.C
+	}
+	return lastR, lastC
+}
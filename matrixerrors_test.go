@@ -0,0 +1,52 @@
+package matrixbitset
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTryVariants(t *testing.T) {
+	m := NewMatrixBitSet(10, 5)
+	if err := m.TrySet(5, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected ErrOutOfBounds, received %v", err)
+	} else {
+		var oob *OutOfBoundsError
+		if !errors.As(err, &oob) || oob.R != 5 || oob.C != 0 || oob.Rows != 5 || oob.Cols != 10 {
+			t.Errorf("Expected offending [5, 0] in 5 x 10, received %+v", oob)
+		}
+	}
+	if err := m.TrySetN(50); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected ErrOutOfBounds for index 50, received %v", err)
+	}
+	if err := m.TrySet(4, 9); err != nil {
+		t.Error(err)
+	}
+	if on, err := m.TryTest(4, 9); err != nil || !on {
+		t.Errorf("Expected [4, 9] on, received %v %v", on, err)
+	}
+	if _, _, err := m.TryNextSet(50); err == nil {
+		t.Error("Expected an error from TryNextSet(50)")
+	}
+	if err := m.TryFill(0, 0, 1<<62, 1); err == nil {
+		t.Error("Expected an error from TryFill past the matrix")
+	}
+	if m.Count() != 1 {
+		t.Errorf("Expected a failed TryFill to leave 1 bit on, received %d", m.Count())
+	}
+}
+
+func TestClippedFill(t *testing.T) {
+	m := NewMatrixBitSet(10, 5)
+	m.FillClipped(3, 8, 100, 100)
+	if m.Count() != 4 {
+		t.Errorf("Expected 4 bits on, received %d", m.Count())
+	}
+	m.FillClipped(20, 20, 5, 5)
+	if m.Count() != 4 {
+		t.Errorf("Expected a box outside the matrix to be a no-op, received %d", m.Count())
+	}
+	m.DrainClipped(4, 0, ^uint(0), ^uint(0))
+	if m.Count() != 2 {
+		t.Errorf("Expected 2 bits on after drain, received %d", m.Count())
+	}
+}
@@ -0,0 +1,448 @@
+package matrixbitset
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// CCITT Group 4 two dimensional coding, ITU-T T.6, as used by bilevel TIFF.
+// Set bits are black, rows go top down, each coded against the row above
+// starting from an imaginary all white row. The changing elements on a row,
+// where a pixel differs from the one to its left, are found a word at a time
+
+// Returned when G4 data ends early or holds a code T.6 doesn't define
+var ErrBadG4 = errors.New("malformed group 4 data")
+
+// Run length codes from T.4 tables 2 and 3, shared by T.6
+var g4WhiteTerminating = [64]string{
+	"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+	"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+	"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+	"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+	"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+	"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+	"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+	"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+}
+
+var g4BlackTerminating = [64]string{
+	"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+	"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+	"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+	"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+	"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+	"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+	"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+	"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+}
+
+// Makeup codes for 64, 128, ... 1728
+var g4WhiteMakeup = [27]string{
+	"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+	"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+	"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+	"010011010", "011000", "010011011",
+}
+
+var g4BlackMakeup = [27]string{
+	"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+	"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+	"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+	"0000001011011", "0000001100100", "0000001100101",
+}
+
+// Makeup codes for 1792, 1856, ... 2560, the same for both colors
+var g4ExtendedMakeup = [13]string{
+	"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+	"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+}
+
+type g4Mode int
+
+const (
+	g4Pass g4Mode = iota
+	g4Horizontal
+	g4V0
+	g4VR1
+	g4VR2
+	g4VR3
+	g4VL1
+	g4VL2
+	g4VL3
+	g4EOL
+)
+
+// T.6 table 1, the vertical modes by a1 - b1 from -3 to 3
+var g4ModeCodes = map[g4Mode]string{
+	g4Pass:       "0001",
+	g4Horizontal: "001",
+	g4V0:         "1",
+	g4VR1:        "011",
+	g4VR2:        "000011",
+	g4VR3:        "0000011",
+	g4VL1:        "010",
+	g4VL2:        "000010",
+	g4VL3:        "0000010",
+	g4EOL:        "000000000001",
+}
+
+var g4Vertical = [7]g4Mode{g4VL3, g4VL2, g4VL1, g4V0, g4VR1, g4VR2, g4VR3}
+
+func g4VerticalOffset(mode g4Mode) int {
+	for i, m := range g4Vertical {
+		if m == mode {
+			return i - 3
+		}
+	}
+	return 0
+}
+
+// A code as its value and bit length
+type g4Code struct {
+	value uint32
+	bits  uint8
+}
+
+func newG4Code(s string) g4Code {
+	code := g4Code{bits: uint8(len(s))}
+	for _, ch := range s {
+		code.value = code.value<<1 | uint32(ch-'0')
+	}
+	return code
+}
+
+// What a code decodes to, bits is 0 where no code starts
+type g4Entry struct {
+	value uint16
+	bits  uint8
+}
+
+// Indexed by the next g4MaxCodeBits bits of the stream, so every
+// code fills the entries of all the bits that can follow it
+type g4Lookup [1 << g4MaxCodeBits]g4Entry
+
+func (lookup *g4Lookup) add(code g4Code, value uint) {
+	shift := g4MaxCodeBits - code.bits
+	first := code.value << shift
+	for i := first; i < first+1<<shift; i++ {
+		lookup[i] = g4Entry{value: uint16(value), bits: code.bits}
+	}
+}
+
+// Both directions of one color's run lengths
+type g4Table struct {
+	encode map[uint]g4Code
+	decode *g4Lookup
+}
+
+func newG4Table(terminating [64]string, makeup [27]string) *g4Table {
+	table := &g4Table{encode: make(map[uint]g4Code), decode: &g4Lookup{}}
+	add := func(run uint, s string) {
+		code := newG4Code(s)
+		table.encode[run] = code
+		table.decode.add(code, run)
+	}
+	for run, s := range terminating {
+		add(uint(run), s)
+	}
+	for i, s := range makeup {
+		add(uint(i+1)*64, s)
+	}
+	for i, s := range g4ExtendedMakeup {
+		add(1792+uint(i)*64, s)
+	}
+	return table
+}
+
+var (
+	g4White = newG4Table(g4WhiteTerminating, g4WhiteMakeup)
+	g4Black = newG4Table(g4BlackTerminating, g4BlackMakeup)
+	g4Modes = func() *g4Lookup {
+		modes := &g4Lookup{}
+		for mode, s := range g4ModeCodes {
+			modes.add(newG4Code(s), uint(mode))
+		}
+		return modes
+	}()
+)
+
+// Longest code in any table, the 13 bit black makeup codes
+const g4MaxCodeBits = 13
+
+// Compresses m to a G4 stream ending in EOFB, padded to a byte
+func (m *MatrixBitSet) EncodeG4() []byte {
+	w := &g4Writer{}
+	width := int(m.C)
+	reference := make([]uint64, m.rowWordCount())
+	refChanges := make([]uint64, m.rowWordCount())
+	row := make([]uint64, m.rowWordCount())
+	changes := make([]uint64, m.rowWordCount())
+	for r := uint(0); r < m.R; r++ {
+		m.rowWords(r, row)
+		g4Changes(row, changes, m.C)
+		a0, color := -1, false
+		for a0 < width {
+			a1 := g4NextChange(changes, a0+1, width)
+			b1, b2 := g4RefChanges(reference, refChanges, a0, color, width)
+			switch {
+			case b2 < a1:
+				w.writeMode(g4Pass)
+				a0 = b2
+			case a1-b1 >= -3 && a1-b1 <= 3:
+				w.writeMode(g4Vertical[a1-b1+3])
+				a0, color = a1, !color
+			default:
+				a2 := g4NextChange(changes, a1+1, width)
+				w.writeMode(g4Horizontal)
+				w.writeRun(a1-maxInt(a0, 0), color)
+				w.writeRun(a2-a1, !color)
+				a0 = a2
+			}
+		}
+		reference, row = row, reference
+		refChanges, changes = changes, refChanges
+	}
+	w.writeMode(g4EOL)
+	w.writeMode(g4EOL)
+	return w.bytes()
+}
+
+// Decodes width x height from a G4 stream, the EOFB is optional
+func DecodeG4(data []byte, width, height uint) (*MatrixBitSet, error) {
+	if err := checkG4Size(len(data), width, height); err != nil {
+		return nil, fmt.Errorf("g4: %w", err)
+	}
+	m := NewMatrixBitSet(width, height)
+	if err := m.decodeG4(data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Every row takes at least one mode code of at least a bit,
+// so a height past the data's bits can be refused unallocated
+func checkG4Size(bytes int, width, height uint) error {
+	if err := checkDimensions(width, height); err != nil {
+		return err
+	}
+	if height > uint(bytes)*8 {
+		return fmt.Errorf("%d bytes can't hold %d rows: %w", bytes, height, ErrBadG4)
+	}
+	return nil
+}
+
+// Fills m from a G4 stream of its size a row at a time
+func (m *MatrixBitSet) decodeG4(data []byte) error {
+	rd := &g4Reader{data: data}
+	words := m.rowWordCount()
+	row := make([]uint64, words)
+	reference := make([]uint64, words)
+	refChanges := make([]uint64, words)
+	w := int(m.C)
+	for r := uint(0); r < m.R; r++ {
+		for i := range row {
+			row[i] = 0
+		}
+		a0, color := -1, false
+		for a0 < w {
+			mode, err := rd.readMode()
+			if err != nil {
+				return fmt.Errorf("row %d: %w", r, err)
+			}
+			b1, b2 := g4RefChanges(reference, refChanges, a0, color, w)
+			start := maxInt(a0, 0)
+			switch mode {
+			case g4Pass:
+				if b2 >= w {
+					return fmt.Errorf("row %d: pass past the end: %w", r, ErrBadG4)
+				}
+				g4Fill(row, start, b2, color)
+				a0 = b2
+			case g4Horizontal:
+				run1, err := rd.readRun(color)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", r, err)
+				}
+				run2, err := rd.readRun(!color)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", r, err)
+				}
+				if start+run1+run2 > w {
+					return fmt.Errorf("row %d: runs past the end: %w", r, ErrBadG4)
+				}
+				g4Fill(row, start, start+run1, color)
+				g4Fill(row, start+run1, start+run1+run2, !color)
+				a0 = start + run1 + run2
+			case g4EOL:
+				return fmt.Errorf("row %d: data ends before %d rows: %w", r, m.R, ErrBadG4)
+			default:
+				a1 := b1 + g4VerticalOffset(mode)
+				if a1 < start || a1 > w || a1 <= a0 && a0 >= 0 {
+					return fmt.Errorf("row %d: vertical mode to %d: %w", r, a1, ErrBadG4)
+				}
+				g4Fill(row, start, a1, color)
+				a0, color = a1, !color
+			}
+		}
+		m.setRowWords(r, row)
+		reference, row = row, reference
+		g4Changes(reference, refChanges, m.C)
+	}
+	return nil
+}
+
+// Bit c of changes is on where pixel c differs from pixel c-1,
+// with an imaginary white pixel before the first
+func g4Changes(row, changes []uint64, width uint) {
+	var carry uint64
+	for w, word := range row {
+		changes[w] = (word ^ (word<<1 | carry)) & rowMask(width, w)
+		carry = word >> (wordSize - 1)
+	}
+}
+
+// First change at or after from, width when there's none
+func g4NextChange(changes []uint64, from, width int) int {
+	if from >= width {
+		return width
+	}
+	w := from >> log2WordSize
+	word := changes[w] &^ (1<<(uint(from)&(wordSize-1)) - 1)
+	for {
+		if word != 0 {
+			return minInt(w<<log2WordSize+bits.TrailingZeros64(word), width)
+		}
+		w++
+		if w == len(changes) {
+			return width
+		}
+		word = changes[w]
+	}
+}
+
+// b1 is the first change on the reference row right of a0 to the opposite
+// of color, b2 the change after it
+func g4RefChanges(reference, changes []uint64, a0 int, color bool, width int) (int, int) {
+	b1 := g4NextChange(changes, a0+1, width)
+	if b1 < width && testRow(reference, uint(b1)) == color {
+		b1 = g4NextChange(changes, b1+1, width)
+	}
+	return b1, g4NextChange(changes, b1+1, width)
+}
+
+// Only black needs writing, rows start white
+func g4Fill(row []uint64, from, to int, black bool) {
+	if !black {
+		return
+	}
+	for c := from; c < to; c++ {
+		row[c>>log2WordSize] |= 1 << (uint(c) & (wordSize - 1))
+	}
+}
+
+// Packs codes most significant bit first, FillOrder 1
+type g4Writer struct {
+	out   []byte
+	acc   uint64
+	count uint
+}
+
+func (w *g4Writer) write(code g4Code) {
+	w.acc = w.acc<<code.bits | uint64(code.value)
+	w.count += uint(code.bits)
+	for w.count >= 8 {
+		w.count -= 8
+		w.out = append(w.out, byte(w.acc>>w.count))
+	}
+}
+
+func (w *g4Writer) writeMode(mode g4Mode) {
+	w.write(newG4Code(g4ModeCodes[mode]))
+}
+
+// Makeup codes as needed, then a terminating code
+func (w *g4Writer) writeRun(run int, black bool) {
+	table := g4White
+	if black {
+		table = g4Black
+	}
+	for run >= 2560 {
+		w.write(table.encode[2560])
+		run -= 2560
+	}
+	if run >= 64 {
+		w.write(table.encode[uint(run&^63)])
+	}
+	w.write(table.encode[uint(run&63)])
+}
+
+func (w *g4Writer) bytes() []byte {
+	if w.count > 0 {
+		w.out = append(w.out, byte(w.acc<<(8-w.count)))
+		w.count = 0
+	}
+	return w.out
+}
+
+type g4Reader struct {
+	data []byte
+	bit  uint
+}
+
+// The next g4MaxCodeBits bits, 0 past the end, and how many bits are left
+func (rd *g4Reader) peek() (uint32, uint) {
+	total := uint(len(rd.data)) * 8
+	if rd.bit >= total {
+		return 0, 0
+	}
+	// 24 bits from the byte holding rd.bit cover the 13 after it
+	at := rd.bit / 8
+	var window uint32
+	for i := at; i < at+3; i++ {
+		window <<= 8
+		if i < uint(len(rd.data)) {
+			window |= uint32(rd.data[i])
+		}
+	}
+	peeked := window >> (24 - g4MaxCodeBits - rd.bit%8) & (1<<g4MaxCodeBits - 1)
+	return peeked, total - rd.bit
+}
+
+// Looks up the code starting at the next bit and skips past it
+func (rd *g4Reader) readCode(lookup *g4Lookup) (uint, error) {
+	peeked, left := rd.peek()
+	entry := lookup[peeked]
+	switch {
+	case entry.bits != 0 && uint(entry.bits) <= left:
+		rd.bit += uint(entry.bits)
+		return uint(entry.value), nil
+	case left < g4MaxCodeBits:
+		return 0, fmt.Errorf("data ends mid code: %w", ErrBadG4)
+	}
+	return 0, fmt.Errorf("unknown code %013b: %w", peeked, ErrBadG4)
+}
+
+func (rd *g4Reader) readMode() (g4Mode, error) {
+	mode, err := rd.readCode(g4Modes)
+	return g4Mode(mode), err
+}
+
+// Makeup codes add up until a terminating code ends the run
+func (rd *g4Reader) readRun(black bool) (int, error) {
+	table := g4White
+	if black {
+		table = g4Black
+	}
+	total := 0
+	for {
+		run, err := rd.readCode(table.decode)
+		if err != nil {
+			return 0, err
+		}
+		total += int(run)
+		if run < 64 {
+			return total, nil
+		}
+	}
+}
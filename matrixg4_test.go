@@ -0,0 +1,91 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// No code may be the start of another read in the same place
+func TestG4CodesArePrefixFree(t *testing.T) {
+	modes := make([]string, 0)
+	for _, s := range g4ModeCodes {
+		modes = append(modes, s)
+	}
+	white := append(append(g4WhiteTerminating[:], g4WhiteMakeup[:]...), g4ExtendedMakeup[:]...)
+	black := append(append(g4BlackTerminating[:], g4BlackMakeup[:]...), g4ExtendedMakeup[:]...)
+	for name, codes := range map[string][]string{"modes": modes, "white": white, "black": black} {
+		for i, a := range codes {
+			for k, b := range codes {
+				if i != k && strings.HasPrefix(b, a) {
+					t.Errorf("%s: %q is a prefix of %q", name, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeG4Known(t *testing.T) {
+	// V0 for the white row, then EOFB: 1 000000000001 000000000001
+	m := NewMatrixBitSet(8, 1)
+	if received := m.EncodeG4(); !bytes.Equal(received, []byte{0x80, 0x08, 0x00, 0x80}) {
+		t.Errorf("Unexpected encoding %x", received)
+	}
+
+	// H (001), white 2 (0111), black 3 (10), then V0 (1) for the end of the row
+	m.Set(0, 2).Set(0, 3).Set(0, 4)
+	if received := m.EncodeG4(); received[0] != 0x2f || received[1]>>6 != 0x01 {
+		t.Errorf("Unexpected encoding %x", received)
+	}
+}
+
+func TestG4RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(23))
+	for trial := 0; trial < 60; trial++ {
+		w, h := uint(1+rnd.Intn(200)), uint(1+rnd.Intn(30))
+		var m *MatrixBitSet
+		if trial%2 == 0 {
+			m = randomMatrix(rnd, w, h, rnd.Float64())
+		} else {
+			// blobs give the vertical and pass modes more to do
+			m = NewMatrixBitSet(w, h)
+			for i := 0; i < 4; i++ {
+				r, c := uint(rnd.Intn(int(h))), uint(rnd.Intn(int(w)))
+				m.FillClipped(r, c, uint(rnd.Intn(10)), uint(rnd.Intn(30)))
+			}
+		}
+		back, err := DecodeG4(m.EncodeG4(), w, h)
+		if err != nil || !sameBits(back, m) {
+			t.Fatalf("%d x %d: round trip differs, %v\n%v", h, w, err, m)
+		}
+	}
+}
+
+func TestG4LongRuns(t *testing.T) {
+	m := NewMatrixBitSet(6000, 4)
+	m.Fill(1, 10, 1, 5800)
+	m.Fill(2, 2559, 1, 2561)
+	back, err := DecodeG4(m.EncodeG4(), m.C, m.R)
+	if err != nil || !sameBits(back, m) {
+		t.Errorf("Long runs round trip differs, %v", err)
+	}
+}
+
+func TestDecodeG4Errors(t *testing.T) {
+	m := randomMatrix(rand.New(rand.NewSource(29)), 40, 10, 0.5)
+	data := m.EncodeG4()
+	if _, err := DecodeG4(data[:len(data)/2], 40, 10); !errors.Is(err, ErrBadG4) {
+		t.Errorf("Expected ErrBadG4 for truncated data, received %v", err)
+	}
+	if _, err := DecodeG4(data, 40, 11); !errors.Is(err, ErrBadG4) {
+		t.Errorf("Expected ErrBadG4 for rows past the EOFB, received %v", err)
+	}
+	if _, err := DecodeG4([]byte{0, 0}, 40, 1); !errors.Is(err, ErrBadG4) {
+		t.Errorf("Expected ErrBadG4 for an unknown code, received %v", err)
+	}
+	if _, err := DecodeG4(data, 0, 10); err == nil {
+		t.Error("Expected an error for a width of 0")
+	}
+}
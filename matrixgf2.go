@@ -0,0 +1,144 @@
+package matrixbitset
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Linear algebra over GF(2), where adding is XOR and multiplying is AND,
+// unlike Mul which adds with OR. Vectors are single col matrices
+
+// Returned by Inverse when the rows aren't independent
+var ErrSingular = errors.New("matrix is singular")
+
+// Returned by Solve when b isn't in the span of the cols
+var ErrNoSolution = errors.New("system has no solution")
+
+// Reduced row echelon form by XORing whole aligned rows, and the col
+// of each pivot in row order, returns a new M2
+func (m *MatrixBitSet) RowEchelon() (*MatrixBitSet, []uint) {
+	rows := m.toRows()
+	pivots := gf2Eliminate(rows, nil, m.C)
+	return fromRows(rows, m.C), pivots
+}
+
+func (m *MatrixBitSet) Rank() uint {
+	return uint(len(gf2Eliminate(m.toRows(), nil, m.C)))
+}
+
+// 1 when m is invertible, 0 otherwise
+func (m *MatrixBitSet) Determinant() (uint, error) {
+	if m.R != m.C {
+		return 0, fmt.Errorf("no determinant for %d x %d, it isn't square", m.R, m.C)
+	}
+	if m.Rank() == m.R {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Gauss-Jordan on [m | I], returns a new M2
+func (m *MatrixBitSet) Inverse() (*MatrixBitSet, error) {
+	if m.R != m.C {
+		return nil, fmt.Errorf("no inverse for %d x %d, it isn't square", m.R, m.C)
+	}
+	rows := m.toRows()
+	inverse := NewIdentity(m.R).toRows()
+	if pivots := gf2Eliminate(rows, inverse, m.C); uint(len(pivots)) != m.R {
+		return nil, ErrSingular
+	}
+	return fromRows(inverse, m.C), nil
+}
+
+// One x with m · x = b, b has a single col and m's rows,
+// x has a single col and m's cols. Free variables are left 0
+func (m *MatrixBitSet) Solve(b *MatrixBitSet) (*MatrixBitSet, error) {
+	if b.C != 1 || b.R != m.R {
+		return nil, fmt.Errorf("can't solve %d x %d against %d x %d", m.R, m.C, b.R, b.C)
+	}
+	rows := m.toRows()
+	rhs := make([][]uint64, m.R)
+	for r := range rhs {
+		rhs[r] = []uint64{0}
+		if b.test(uint(r)) {
+			rhs[r][0] = 1
+		}
+	}
+	pivots := gf2Eliminate(rows, rhs, m.C)
+	// rows past the pivots are all zero on the left, so must be on the right
+	for r := len(pivots); r < len(rhs); r++ {
+		if rhs[r][0] != 0 {
+			return nil, ErrNoSolution
+		}
+	}
+	x := NewMatrixBitSet(1, m.C)
+	for r, col := range pivots {
+		if rhs[r][0] != 0 {
+			x.set(col)
+		}
+	}
+	return x, nil
+}
+
+// A basis for the x with m · x = 0, one per col of the result
+// which has m's cols as rows and one col per free variable
+func (m *MatrixBitSet) NullSpace() *MatrixBitSet {
+	rows := m.toRows()
+	pivots := gf2Eliminate(rows, nil, m.C)
+	isPivot := make([]bool, m.C)
+	for _, col := range pivots {
+		isPivot[col] = true
+	}
+	free := make([]uint, 0, m.C-uint(len(pivots)))
+	for col := uint(0); col < m.C; col++ {
+		if !isPivot[col] {
+			free = append(free, col)
+		}
+	}
+	basis := NewMatrixBitSet(uint(len(free)), m.C)
+	for k, f := range free {
+		// free variable f on, each pivot variable cancels its row's f
+		basis.set(basis.index(f, uint(k)))
+		for r, col := range pivots {
+			if testRow(rows[r], f) {
+				basis.set(basis.index(col, uint(k)))
+			}
+		}
+	}
+	return basis
+}
+
+// Reduces rows to reduced row echelon form over their first cols,
+// applying every row operation to the matching row of extra as well.
+// Returns the pivot cols, pivot row i ends up as rows[i]
+func gf2Eliminate(rows, extra [][]uint64, cols uint) []uint {
+	pivots := make([]uint, 0)
+	next := 0
+	for col := uint(0); col < cols && next < len(rows); col++ {
+		found := -1
+		for r := next; r < len(rows); r++ {
+			if testRow(rows[r], col) {
+				found = r
+				break
+			}
+		}
+		if found < 0 {
+			continue
+		}
+		rows[next], rows[found] = rows[found], rows[next]
+		if extra != nil {
+			extra[next], extra[found] = extra[found], extra[next]
+		}
+		for r := range rows {
+			if r != next && testRow(rows[r], col) {
+				xorRow(rows[r], rows[next])
+				if extra != nil {
+					xorRow(extra[r], extra[next])
+				}
+			}
+		}
+		pivots = append(pivots, col)
+		next++
+	}
+	return pivots
+}
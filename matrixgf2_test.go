@@ -0,0 +1,117 @@
+package matrixbitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Every x in GF(2)^n, as single col matrices
+func allVectors(n uint) []*MatrixBitSet {
+	vectors := make([]*MatrixBitSet, 0, 1<<n)
+	for bits := uint(0); bits < 1<<n; bits++ {
+		x := NewMatrixBitSet(1, n)
+		for i := uint(0); i < n; i++ {
+			if bits&(1<<i) != 0 {
+				x.set(i)
+			}
+		}
+		vectors = append(vectors, x)
+	}
+	return vectors
+}
+
+// Product with XOR for sum, Mul is the boolean one
+func bruteGF2Mul(a, b *MatrixBitSet) *MatrixBitSet {
+	product := NewMatrixBitSet(b.C, a.R)
+	for r := uint(0); r < a.R; r++ {
+		for c := uint(0); c < b.C; c++ {
+			on := false
+			for k := uint(0); k < a.C; k++ {
+				on = on != (a.Test(r, k) && b.Test(k, c))
+			}
+			if on {
+				product.Set(r, c)
+			}
+		}
+	}
+	return product
+}
+
+func isZero(m *MatrixBitSet) bool {
+	return m.Count() == 0
+}
+
+func TestGF2AgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	for trial := 0; trial < 60; trial++ {
+		rows, cols := uint(1+rnd.Intn(6)), uint(1+rnd.Intn(6))
+		m := randomMatrix(rnd, cols, rows, 0.4)
+
+		// rank is log2 of the size of the image
+		image := make(map[uint64]bool)
+		kernel := 0
+		for _, x := range allVectors(cols) {
+			y := bruteGF2Mul(m, x)
+			image[y.B[0]] = true
+			if isZero(y) {
+				kernel++
+			}
+		}
+		if expected := uint(len(image)); 1<<m.Rank() != expected {
+			t.Errorf("%v: expected rank %d, received %d", m.toRows(), expected, m.Rank())
+		}
+
+		basis := m.NullSpace()
+		if uint(1)<<basis.C != uint(kernel) {
+			t.Errorf("Expected a null space of %d vectors, basis has %d", kernel, basis.C)
+		}
+		if basis.C > 0 && !isZero(bruteGF2Mul(m, basis)) {
+			t.Error("Expected m times the null space to be zero")
+		}
+
+		for _, b := range allVectors(rows) {
+			x, err := m.Solve(b)
+			if image[b.B[0]] {
+				if err != nil {
+					t.Errorf("Expected a solution for %v", b.B)
+				} else if !sameBits(bruteGF2Mul(m, x), b) {
+					t.Errorf("Solve gave an x that doesn't produce %v", b.B)
+				}
+			} else if err != ErrNoSolution {
+				t.Errorf("Expected ErrNoSolution, received %v", err)
+			}
+		}
+
+		if rows == cols {
+			det, _ := m.Determinant()
+			inverse, err := m.Inverse()
+			if (det == 1) != (err == nil) {
+				t.Errorf("Determinant %d disagrees with Inverse %v", det, err)
+			}
+			if err == nil {
+				if !sameBits(bruteGF2Mul(m, inverse), NewIdentity(rows)) {
+					t.Error("Expected m times its inverse to be the identity")
+				}
+			} else if err != ErrSingular {
+				t.Errorf("Expected ErrSingular, received %v", err)
+			}
+		}
+	}
+}
+
+func TestRowEchelon(t *testing.T) {
+	m := shapeFromRows([]string{
+		".##.#",
+		".##..",
+		"#...#",
+	})
+	echelon, pivots := m.RowEchelon()
+	expected := shapeFromRows([]string{
+		"#....",
+		".##..",
+		"....#",
+	})
+	if !sameBits(echelon, expected) || len(pivots) != 3 || pivots[0] != 0 || pivots[1] != 1 || pivots[2] != 4 {
+		t.Errorf("Unexpected echelon form %v, pivots %v", echelon.toRows(), pivots)
+	}
+}
@@ -0,0 +1,273 @@
+package matrixbitset
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// An R-tree over polygons, bulk loaded with Sort-Tile-Recursive
+// Leutenegger, Lopez and Edgington, ICDE 1997. Every node is keyed by
+// the MatrixBounds rectangle around what's below it, queries come back
+// as the *Polygon values the index was built from
+type PolygonIndex struct {
+	M    *MatrixBitSet
+	root *indexNode
+	size int
+}
+
+type indexNode struct {
+	bounds   *MatrixBounds
+	children []*indexNode
+	polygon  *Polygon
+}
+
+// Entries per node
+const indexFanout = 16
+
+// m ties the MatrixBounds together, it should be the matrix
+// the polygons were extracted from
+func NewPolygonIndex(m *MatrixBitSet, polygons []*Polygon) *PolygonIndex {
+	level := make([]*indexNode, 0, len(polygons))
+	for _, p := range polygons {
+		if len(p.Outer) == 0 {
+			continue
+		}
+		level = append(level, &indexNode{bounds: ringRect(m, p.Outer), polygon: p})
+	}
+	pi := &PolygonIndex{M: m, size: len(level)}
+	if len(level) == 0 {
+		return pi
+	}
+	for len(level) > 1 {
+		level = strPack(m, level)
+	}
+	pi.root = level[0]
+	return pi
+}
+
+func (pi *PolygonIndex) Len() int {
+	return pi.size
+}
+
+// Polygons containing the point, boundary included
+func (pi *PolygonIndex) AtPoint(r, c float64) []*Polygon {
+	found := make([]*Polygon, 0)
+	pi.search(func(b *MatrixBounds) bool {
+		return float64(b.MinR) <= r && r <= float64(b.MaxR) && float64(b.MinC) <= c && c <= float64(b.MaxC)
+	}, func(p *Polygon) {
+		if p.Contains(r, c) {
+			found = append(found, p)
+		}
+	})
+	return found
+}
+
+// Polygons sharing any point with the rectangle, edges included
+func (pi *PolygonIndex) Intersecting(rect *MatrixBounds) []*Polygon {
+	found := make([]*Polygon, 0)
+	pi.search(rect.Overlaps, func(p *Polygon) {
+		if p.intersectsRect(rect) {
+			found = append(found, p)
+		}
+	})
+	return found
+}
+
+// Polygons whose bounding rectangle overlaps, without the exact check
+func (pi *PolygonIndex) Candidates(rect *MatrixBounds) []*Polygon {
+	found := make([]*Polygon, 0)
+	pi.search(rect.Overlaps, func(p *Polygon) {
+		found = append(found, p)
+	})
+	return found
+}
+
+// Up to k polygons closest to the point, nearest first
+// A polygon containing the point is at distance 0
+func (pi *PolygonIndex) Nearest(r, c float64, k int) []*Polygon {
+	found := make([]*Polygon, 0, k)
+	if pi.root == nil || k <= 0 {
+		return found
+	}
+	queue := &indexQueue{{node: pi.root, dist: rectDistance2(pi.root.bounds, r, c)}}
+	for queue.Len() > 0 && len(found) < k {
+		item := heap.Pop(queue).(indexItem)
+		switch {
+		case item.exact:
+			found = append(found, item.node.polygon)
+		case item.node.polygon != nil:
+			// re-queue with the real distance, which is never less than the rectangle's
+			heap.Push(queue, indexItem{node: item.node, dist: polygonDistance2(item.node.polygon, r, c), exact: true})
+		default:
+			for _, child := range item.node.children {
+				heap.Push(queue, indexItem{node: child, dist: rectDistance2(child.bounds, r, c)})
+			}
+		}
+	}
+	return found
+}
+
+func (pi *PolygonIndex) search(overlaps func(*MatrixBounds) bool, visit func(*Polygon)) {
+	if pi.root == nil {
+		return
+	}
+	stack := []*indexNode{pi.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !overlaps(node.bounds) {
+			continue
+		}
+		if node.polygon != nil {
+			visit(node.polygon)
+			continue
+		}
+		stack = append(stack, node.children...)
+	}
+}
+
+// One level of STR, slabs by center col then runs by center row
+func strPack(m *MatrixBitSet, nodes []*indexNode) []*indexNode {
+	leaves := (len(nodes) + indexFanout - 1) / indexFanout
+	slabs := int(math.Ceil(math.Sqrt(float64(leaves))))
+	slabSize := slabs * indexFanout
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].bounds.MinC+nodes[i].bounds.MaxC < nodes[j].bounds.MinC+nodes[j].bounds.MaxC
+	})
+	parents := make([]*indexNode, 0, leaves)
+	for s := 0; s < len(nodes); s += slabSize {
+		slab := nodes[s:minInt(s+slabSize, len(nodes))]
+		sort.SliceStable(slab, func(i, j int) bool {
+			return slab[i].bounds.MinR+slab[i].bounds.MaxR < slab[j].bounds.MinR+slab[j].bounds.MaxR
+		})
+		for n := 0; n < len(slab); n += indexFanout {
+			children := append([]*indexNode(nil), slab[n:minInt(n+indexFanout, len(slab))]...)
+			bounds := children[0].bounds
+			for _, child := range children[1:] {
+				bounds = unionRect(bounds, child.bounds)
+			}
+			parents = append(parents, &indexNode{bounds: bounds, children: children})
+		}
+	}
+	return parents
+}
+
+// Vertexes can sit on corners one past the last row and col, so node
+// rectangles only carry extents, not the pixel indexes NewRectBounds caches
+func ringRect(m *MatrixBitSet, ring LinearRing) *MatrixBounds {
+	rect := &MatrixBounds{M: m, MinR: ring[0].r, MinC: ring[0].c, MaxR: ring[0].r, MaxC: ring[0].c}
+	for _, pt := range ring[1:] {
+		rect.MinR, rect.MinC = minUint(rect.MinR, pt.r), minUint(rect.MinC, pt.c)
+		rect.MaxR, rect.MaxC = maxUint(rect.MaxR, pt.r), maxUint(rect.MaxC, pt.c)
+	}
+	return rect
+}
+
+func unionRect(a, b *MatrixBounds) *MatrixBounds {
+	return &MatrixBounds{M: a.M, MinR: minUint(a.MinR, b.MinR), MinC: minUint(a.MinC, b.MinC),
+		MaxR: maxUint(a.MaxR, b.MaxR), MaxC: maxUint(a.MaxC, b.MaxC)}
+}
+
+// Any vertex in the rectangle, any corner in the polygon or any edges crossing
+func (p *Polygon) intersectsRect(rect *MatrixBounds) bool {
+	stride := p.Outer[0].stride
+	corners := LinearRing{
+		NewMatrixPos(rect.MinR, rect.MinC, stride),
+		NewMatrixPos(rect.MinR, rect.MaxC, stride),
+		NewMatrixPos(rect.MaxR, rect.MaxC, stride),
+		NewMatrixPos(rect.MaxR, rect.MinC, stride),
+	}
+	for _, corner := range corners {
+		if p.Contains(float64(corner.r), float64(corner.c)) {
+			return true
+		}
+	}
+	for _, ring := range p.rings() {
+		for i, pt := range ring {
+			if pt.r >= rect.MinR && pt.r <= rect.MaxR && pt.c >= rect.MinC && pt.c <= rect.MaxC {
+				return true
+			}
+			if i+1 == len(ring) {
+				continue
+			}
+			for k := range corners {
+				if segmentsIntersect(pt, ring[i+1], corners[k], corners[(k+1)%4]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Squared, 0 inside the rectangle
+func rectDistance2(b *MatrixBounds, r, c float64) float64 {
+	dr := math.Max(0, math.Max(float64(b.MinR)-r, r-float64(b.MaxR)))
+	dc := math.Max(0, math.Max(float64(b.MinC)-c, c-float64(b.MaxC)))
+	return dr*dr + dc*dc
+}
+
+// Squared, 0 inside the polygon, otherwise to the closest edge
+func polygonDistance2(p *Polygon, r, c float64) float64 {
+	if p.Contains(r, c) {
+		return 0
+	}
+	best := math.Inf(1)
+	for _, ring := range p.rings() {
+		for i := 0; i+1 < len(ring); i++ {
+			best = math.Min(best, segmentDistance2(ring[i], ring[i+1], r, c))
+		}
+		if len(ring) == 1 {
+			best = math.Min(best, segmentDistance2(ring[0], ring[0], r, c))
+		}
+	}
+	return best
+}
+
+func segmentDistance2(a, b MatrixPos, r, c float64) float64 {
+	ar, ac := float64(a.r), float64(a.c)
+	dr, dc := float64(b.r)-ar, float64(b.c)-ac
+	t := 0.0
+	if length2 := dr*dr + dc*dc; length2 > 0 {
+		t = math.Max(0, math.Min(1, ((r-ar)*dr+(c-ac)*dc)/length2))
+	}
+	pr, pc := ar+t*dr-r, ac+t*dc-c
+	return pr*pr + pc*pc
+}
+
+type indexItem struct {
+	node  *indexNode
+	dist  float64
+	exact bool
+}
+
+// Min heap on distance, exact polygon distances win ties
+type indexQueue []indexItem
+
+func (iq indexQueue) Len() int {
+	return len(iq)
+}
+
+func (iq indexQueue) Less(x, y int) bool {
+	if iq[x].dist == iq[y].dist {
+		return iq[x].exact && !iq[y].exact
+	}
+	return iq[x].dist < iq[y].dist
+}
+
+func (iq indexQueue) Swap(x, y int) {
+	iq[x], iq[y] = iq[y], iq[x]
+}
+
+func (iq *indexQueue) Push(x interface{}) {
+	*iq = append(*iq, x.(indexItem))
+}
+
+func (iq *indexQueue) Pop() interface{} {
+	old := *iq
+	item := old[len(old)-1]
+	*iq = old[:len(old)-1]
+	return item
+}
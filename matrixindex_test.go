@@ -0,0 +1,136 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func samePolygons(a, b []*Polygon) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[*Polygon]int)
+	for _, p := range a {
+		seen[p]++
+	}
+	for _, p := range b {
+		seen[p]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPolygonIndexQueries(t *testing.T) {
+	// 36 pieces, more than one node holds, one with a hole
+	// and one running to the last corner
+	m, _ := ParseASCII(`
+		..............................
+		.##...##...##...##...##...##..
+		.##...##...##...##...##...##..
+		......##...##........##...##..
+		...........##.............##..
+		..............................
+		.##...####.####.##...###..####
+		.##...#..#.####.##...###..####
+		.##...#..#......##...###......
+		......####...........###......
+		..............................
+		.##...####.###..##...####.###.
+		.##...####.###..##...####.###.
+		.##........###..##........###.
+		.##.............##............
+		..............................
+		.##...##...##...##...##...##..
+		.##...##...##...##...##...##..
+		......##...##........##...##..
+		...........##.............##..
+		..............................
+		.##...###..####.##...###..####
+		.##...###..####.##...###..####
+		.##...###.......##...###......
+		......###............###......
+		..............................
+		.##...####.###..##...####.####
+		.##...####.###..##...####.####
+		.##........###..##........####
+		.##.............##........####
+	`)
+	polygons, _ := m.ExtractCrackPolygons()
+	index := NewPolygonIndex(m, polygons)
+	if index.Len() != 36 {
+		t.Fatalf("Expected 36 polygons indexed, received %d", index.Len())
+	}
+	// corners run to R and C, one past the last pixel
+	if root := index.root.bounds; root.MaxR != m.R || root.MaxC != m.C {
+		t.Errorf("Expected the root to reach [%d, %d], received [%d, %d]", m.R, m.C, root.MaxR, root.MaxC)
+	}
+	for _, q := range [][2]float64{{2, 2}, {8, 8}, {7, 7.5}, {0, 0}, {30, 30}, {12.5, 27}} {
+		expected := make([]*Polygon, 0)
+		for _, p := range polygons {
+			if p.Contains(q[0], q[1]) {
+				expected = append(expected, p)
+			}
+		}
+		if found := index.AtPoint(q[0], q[1]); !samePolygons(found, expected) {
+			t.Errorf("AtPoint %v: expected %d polygons, received %d", q, len(expected), len(found))
+		}
+	}
+
+	rect := NewRectBounds(m, 5, 10, 20, 15)
+	expected := make([]*Polygon, 0)
+	for _, p := range polygons {
+		if p.intersectsRect(rect) {
+			expected = append(expected, p)
+		}
+	}
+	if found := index.Intersecting(rect); len(expected) == 0 || !samePolygons(found, expected) {
+		t.Errorf("Intersecting: expected %d polygons, received %d", len(expected), len(found))
+	}
+	// inside the hole touches nothing
+	if found := index.Intersecting(NewRectBounds(m, 8, 8, 8, 8)); len(found) != 0 {
+		t.Errorf("Expected nothing within the hole, received %d", len(found))
+	}
+}
+
+func TestPolygonIndexNearest(t *testing.T) {
+	m, _ := ParseASCII(`
+		##....#....##
+		##.........##
+		......###....
+		.#....###..#.
+		......###....
+		###.........#
+		.......##...#
+	`)
+	polygons, _ := m.ExtractCrackPolygons()
+	index := NewPolygonIndex(m, polygons)
+	r, c := 3.5, 3.0
+	nearest := index.Nearest(r, c, 5)
+	if len(nearest) != 5 {
+		t.Fatalf("Expected 5 nearest, received %d", len(nearest))
+	}
+	last := -1.0
+	for _, p := range nearest {
+		d := polygonDistance2(p, r, c)
+		if d < last {
+			t.Errorf("Expected nearest first, %v came after %v", d, last)
+		}
+		last = d
+	}
+	// nothing left out is closer than the farthest returned
+	closer := 0
+	for _, p := range polygons {
+		if polygonDistance2(p, r, c) < last {
+			closer++
+		}
+	}
+	if closer > 4 {
+		t.Errorf("Expected at most 4 polygons closer than the 5th, found %d", closer)
+	}
+	if len(index.Nearest(r, c, 1000)) != len(polygons) {
+		t.Error("Expected Nearest to stop at every polygon")
+	}
+}
@@ -0,0 +1,429 @@
+package matrixbitset
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSON and text forms for exchanging matrices and their geometry.
+// A MatrixPos on its own carries its stride, [r, c, stride] in both JSON
+// and text, so it round trips as a map key too. Polygon and MatrixBounds
+// write their vertexes as [r, c] and carry one stride for all of them
+
+func (mp MatrixPos) MarshalJSON() ([]byte, error) {
+	if !mp.Valid() {
+		return []byte("null"), nil
+	}
+	return json.Marshal([3]uint{mp.r, mp.c, mp.stride})
+}
+
+// null is InvalidPos, anything else needs its stride
+func (mp *MatrixPos) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*mp = InvalidPos
+		return nil
+	}
+	var rcs []uint
+	if err := json.Unmarshal(data, &rcs); err != nil {
+		return fmt.Errorf("matrix pos: %v", err)
+	}
+	if len(rcs) != 3 {
+		return fmt.Errorf("matrix pos: expected [r, c, stride], received %d values", len(rcs))
+	}
+	if rcs[1] >= rcs[2] {
+		return fmt.Errorf("matrix pos: col %d is past its stride %d", rcs[1], rcs[2])
+	}
+	*mp = NewMatrixPos(rcs[0], rcs[1], rcs[2])
+	return nil
+}
+
+// Like String but with the stride, null for InvalidPos
+func (mp MatrixPos) MarshalText() ([]byte, error) {
+	if !mp.Valid() {
+		return []byte("null"), nil
+	}
+	return []byte(fmt.Sprintf("[%d, %d, %d]", mp.r, mp.c, mp.stride)), nil
+}
+
+func (mp *MatrixPos) UnmarshalText(text []byte) error {
+	if string(text) == "null" {
+		*mp = InvalidPos
+		return nil
+	}
+	var r, c, stride uint
+	if _, err := fmt.Sscanf(string(text), "[%d, %d, %d]", &r, &c, &stride); err != nil {
+		return fmt.Errorf("matrix pos %q: %v", text, err)
+	}
+	if c >= stride {
+		return fmt.Errorf("matrix pos: col %d is past its stride %d", c, stride)
+	}
+	*mp = NewMatrixPos(r, c, stride)
+	return nil
+}
+
+func (mp MatrixPos) withStride(stride uint) MatrixPos {
+	return NewMatrixPos(mp.r, mp.c, stride)
+}
+
+type polygonJSON struct {
+	Stride uint        `json:"stride"`
+	Outer  [][2]uint   `json:"outer"`
+	Holes  [][][2]uint `json:"holes,omitempty"`
+}
+
+func (p *Polygon) MarshalJSON() ([]byte, error) {
+	pj := polygonJSON{Stride: p.stride(), Outer: ringToJSON(p.Outer)}
+	for _, hole := range p.Holes {
+		pj.Holes = append(pj.Holes, ringToJSON(hole))
+	}
+	return json.Marshal(pj)
+}
+
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	var pj polygonJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return fmt.Errorf("polygon: %v", err)
+	}
+	outer, err := ringFromJSON(pj.Outer, pj.Stride)
+	if err != nil {
+		return fmt.Errorf("polygon: %v", err)
+	}
+	holes := make([]LinearRing, 0, len(pj.Holes))
+	for _, h := range pj.Holes {
+		hole, err := ringFromJSON(h, pj.Stride)
+		if err != nil {
+			return fmt.Errorf("polygon: %v", err)
+		}
+		holes = append(holes, hole)
+	}
+	p.Outer, p.Holes = outer, holes
+	return nil
+}
+
+func ringToJSON(ring LinearRing) [][2]uint {
+	rcs := make([][2]uint, len(ring))
+	for i, mp := range ring {
+		rcs[i] = [2]uint{mp.r, mp.c}
+	}
+	return rcs
+}
+
+func ringFromJSON(rcs [][2]uint, stride uint) (LinearRing, error) {
+	ring := make(LinearRing, len(rcs))
+	for i, rc := range rcs {
+		if rc[1] >= stride {
+			return nil, fmt.Errorf("col %d is past the stride %d", rc[1], stride)
+		}
+		ring[i] = NewMatrixPos(rc[0], rc[1], stride)
+	}
+	return ring, nil
+}
+
+// Well known text, POLYGON ((c r, c r, ...), (...)) with x the col
+// The stride isn't carried, it's set to the widest col + 1
+func (p *Polygon) MarshalText() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("POLYGON (")
+	for i, ring := range p.rings() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for k, mp := range ring {
+			if k > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%d %d", mp.c, mp.r)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(")")
+	return []byte(b.String()), nil
+}
+
+func (p *Polygon) UnmarshalText(text []byte) error {
+	body := strings.TrimSpace(string(text))
+	if !strings.HasPrefix(strings.ToUpper(body), "POLYGON") {
+		return fmt.Errorf("polygon %q: expected POLYGON", text)
+	}
+	body = strings.TrimSpace(body[len("POLYGON"):])
+	if len(body) < 2 || body[0] != '(' || body[len(body)-1] != ')' {
+		return fmt.Errorf("polygon %q: expected its rings in parentheses", text)
+	}
+	body = strings.TrimSpace(body[1 : len(body)-1])
+
+	rings := make([]LinearRing, 0)
+	widest := uint(0)
+	for len(body) > 0 {
+		end := strings.IndexByte(body, ')')
+		if body[0] != '(' || end < 0 {
+			return fmt.Errorf("polygon %q: expected a ring in parentheses", text)
+		}
+		ring := make(LinearRing, 0)
+		for _, point := range strings.Split(body[1:end], ",") {
+			fields := strings.Fields(point)
+			if len(fields) != 2 {
+				return fmt.Errorf("polygon %q: %q isn't a point", text, point)
+			}
+			c, errC := strconv.ParseUint(fields[0], 10, 0)
+			r, errR := strconv.ParseUint(fields[1], 10, 0)
+			if errC != nil || errR != nil {
+				return fmt.Errorf("polygon %q: %q isn't on the grid", text, point)
+			}
+			widest = maxUint(widest, uint(c))
+			ring = append(ring, NewMatrixPos(uint(r), uint(c), 0))
+		}
+		rings = append(rings, ring)
+		body = strings.TrimPrefix(strings.TrimSpace(body[end+1:]), ",")
+		body = strings.TrimSpace(body)
+	}
+	if len(rings) == 0 {
+		return fmt.Errorf("polygon %q: no rings", text)
+	}
+	p.Outer, p.Holes = rings[0], rings[1:]
+	p.setStride(widest + 1)
+	return nil
+}
+
+// The stride of the first vertex, 0 when there are none
+func (p *Polygon) stride() uint {
+	if len(p.Outer) == 0 {
+		return 0
+	}
+	return p.Outer[0].stride
+}
+
+func (p *Polygon) setStride(stride uint) {
+	for _, ring := range p.rings() {
+		for i := range ring {
+			ring[i] = ring[i].withStride(stride)
+		}
+	}
+}
+
+// The bits of M aren't carried, only its size, so an unmarshaled
+// MatrixBounds is tied to an empty matrix of the same size
+type boundsJSON struct {
+	Min      [2]uint     `json:"min"`
+	Max      [2]uint     `json:"max"`
+	Matrix   *matrixSize `json:"matrix,omitempty"`
+	Vertexes [][2]uint   `json:"vertexes,omitempty"`
+}
+
+type matrixSize struct {
+	Rows uint `json:"rows"`
+	Cols uint `json:"cols"`
+}
+
+func (mb *MatrixBounds) MarshalJSON() ([]byte, error) {
+	bj := boundsJSON{Min: [2]uint{mb.MinR, mb.MinC}, Max: [2]uint{mb.MaxR, mb.MaxC}}
+	if mb.M != nil {
+		bj.Matrix = &matrixSize{Rows: mb.M.R, Cols: mb.M.C}
+	}
+	for i := range mb.vertx {
+		bj.Vertexes = append(bj.Vertexes, [2]uint{mb.verty[i], mb.vertx[i]})
+	}
+	return json.Marshal(bj)
+}
+
+func (mb *MatrixBounds) UnmarshalJSON(data []byte) error {
+	var bj boundsJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return fmt.Errorf("matrix bounds: %v", err)
+	}
+	var m *MatrixBitSet
+	if bj.Matrix != nil {
+		m = NewMatrixBitSet(bj.Matrix.Cols, bj.Matrix.Rows)
+	}
+	mb.setRect(m, bj.Min[0], bj.Min[1], bj.Max[0], bj.Max[1])
+	if len(bj.Vertexes) > 0 {
+		mb.vertx, mb.verty = make([]uint, 0, len(bj.Vertexes)), make([]uint, 0, len(bj.Vertexes))
+		for _, rc := range bj.Vertexes {
+			mb.vertx, mb.verty = append(mb.vertx, rc[1]), append(mb.verty, rc[0])
+		}
+	}
+	return nil
+}
+
+// [minR, minC]-[maxR, maxC], not tied to any matrix when read back
+func (mb *MatrixBounds) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("[%d, %d]-[%d, %d]", mb.MinR, mb.MinC, mb.MaxR, mb.MaxC)), nil
+}
+
+func (mb *MatrixBounds) UnmarshalText(text []byte) error {
+	var minR, minC, maxR, maxC uint
+	if _, err := fmt.Sscanf(string(text), "[%d, %d]-[%d, %d]", &minR, &minC, &maxR, &maxC); err != nil {
+		return fmt.Errorf("matrix bounds %q: %v", text, err)
+	}
+	mb.setRect(nil, minR, minC, maxR, maxC)
+	return nil
+}
+
+// Like NewRectBounds, but m may be nil
+func (mb *MatrixBounds) setRect(m *MatrixBitSet, minR, minC, maxR, maxC uint) {
+	if m != nil {
+		*mb = *NewRectBounds(m, minR, minC, maxR, maxC)
+		return
+	}
+	*mb = MatrixBounds{MinR: minR, MinC: minC, MaxR: maxR, MaxC: maxC}
+	mb.vertx = []uint{minC, maxC, maxC, minC}
+	mb.verty = []uint{minR, minR, maxR, maxR}
+}
+
+// How MatrixBitSet bits are written to JSON
+const (
+	// Bit n of the matrix is bit n%8 of byte n/8, base64 encoded
+	EncodingBase64 = "base64"
+	// Run lengths over the bits in row order, starting with a clear run
+	EncodingRLE = "rle"
+)
+
+type matrixJSON struct {
+	Rows     uint   `json:"rows"`
+	Cols     uint   `json:"cols"`
+	Encoding string `json:"encoding"`
+	Data     string `json:"data,omitempty"`
+	Runs     []uint `json:"runs,omitempty"`
+}
+
+// Base64 encoded, see MarshalJSONRLE for sparse matrices
+func (m *MatrixBitSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(matrixJSON{Rows: m.R, Cols: m.C, Encoding: EncodingBase64,
+		Data: base64.StdEncoding.EncodeToString(m.packedBytes())})
+}
+
+func (m *MatrixBitSet) MarshalJSONRLE() ([]byte, error) {
+	return json.Marshal(matrixJSON{Rows: m.R, Cols: m.C, Encoding: EncodingRLE, Runs: m.runs()})
+}
+
+// Takes either encoding
+func (m *MatrixBitSet) UnmarshalJSON(data []byte) error {
+	var mj matrixJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return fmt.Errorf("matrix: %v", err)
+	}
+	// only a really empty matrix skips the size checks, a product
+	// that wraps to 0 doesn't, and the data must fit the size
+	// before anything is allocated
+	if mj.Rows != 0 && mj.Cols != 0 {
+		if err := checkDimensions(mj.Cols, mj.Rows); err != nil {
+			return fmt.Errorf("matrix: %v", err)
+		}
+	}
+	total := mj.Rows * mj.Cols
+	var result *MatrixBitSet
+	switch mj.Encoding {
+	case EncodingBase64:
+		if expected := base64.StdEncoding.EncodedLen(int((total + 7) / 8)); len(mj.Data) != expected {
+			return fmt.Errorf("matrix: %d base64 characters for %d x %d, expected %d", len(mj.Data), mj.Rows, mj.Cols, expected)
+		}
+		packed, err := base64.StdEncoding.DecodeString(mj.Data)
+		if err != nil {
+			return fmt.Errorf("matrix: %v", err)
+		}
+		if uint(len(packed)) != (total+7)/8 {
+			return fmt.Errorf("matrix: %d bytes for %d x %d, expected %d", len(packed), mj.Rows, mj.Cols, (total+7)/8)
+		}
+		result = NewMatrixBitSet(mj.Cols, mj.Rows)
+		for i, b := range packed {
+			result.B[i/8] |= uint64(b) << (8 * uint(i%8))
+		}
+		if len(result.B) > 0 {
+			result.B[len(result.B)-1] &= rowMask(total, len(result.B)-1)
+		}
+	case EncodingRLE:
+		if err := checkRuns(mj.Runs, mj.Rows, mj.Cols); err != nil {
+			return fmt.Errorf("matrix: %v", err)
+		}
+		result = NewMatrixBitSet(mj.Cols, mj.Rows)
+		if err := result.setRuns(mj.Runs); err != nil {
+			return fmt.Errorf("matrix: %v", err)
+		}
+	default:
+		return fmt.Errorf("matrix: unknown encoding %q", mj.Encoding)
+	}
+	*m = *result
+	return nil
+}
+
+// The ASCII form, see FormatASCII and ParseASCII
+func (m *MatrixBitSet) MarshalText() ([]byte, error) {
+	return []byte(m.FormatASCII()), nil
+}
+
+func (m *MatrixBitSet) UnmarshalText(text []byte) error {
+	result, err := ParseASCII(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *result
+	return nil
+}
+
+// A byte per 8 bits, bits past the last pixel cleared
+func (m *MatrixBitSet) packedBytes() []byte {
+	total := m.R * m.C
+	packed := make([]byte, (total+7)/8)
+	for i := range packed {
+		w := i / 8
+		packed[i] = byte((m.B[w] & rowMask(total, w)) >> (8 * uint(i%8)))
+	}
+	return packed
+}
+
+// Alternating clear and set run lengths in row order, starting clear
+func (m *MatrixBitSet) runs() []uint {
+	total := m.R * m.C
+	runs := make([]uint, 0)
+	at := uint(0)
+	for i, ok := m.nextSet(0); ok && i < total; i, ok = m.nextSet(at) {
+		end := i
+		for end < total && m.test(end) {
+			end++
+		}
+		runs = append(runs, i-at, end-i)
+		at = end
+	}
+	if at < total {
+		runs = append(runs, total-at)
+	}
+	return runs
+}
+
+// Sets the bits of every odd run, the inverse of runs
+// m should be empty, the runs must cover it exactly
+func (m *MatrixBitSet) setRuns(runs []uint) error {
+	if err := checkRuns(runs, m.R, m.C); err != nil {
+		return err
+	}
+	n := uint(0)
+	for i, run := range runs {
+		if i%2 == 1 {
+			for k := n; k < n+run; k++ {
+				m.set(k)
+			}
+		}
+		n += run
+	}
+	return nil
+}
+
+// Errors unless runs add up to exactly rows x cols, which mustn't
+// overflow, so a size can be checked before it's allocated
+func checkRuns(runs []uint, rows, cols uint) error {
+	total := rows * cols
+	n := uint(0)
+	for _, run := range runs {
+		if run > total-n {
+			return fmt.Errorf("runs cover more than %d x %d", rows, cols)
+		}
+		n += run
+	}
+	if n != total {
+		return fmt.Errorf("runs cover %d of %d x %d", n, rows, cols)
+	}
+	return nil
+}
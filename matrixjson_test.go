@@ -0,0 +1,158 @@
+package matrixbitset
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMatrixPosJSON(t *testing.T) {
+	data, err := json.Marshal([]MatrixPos{NewMatrixPos(2, 3, 10), InvalidPos})
+	if err != nil || string(data) != "[[2,3,10],null]" {
+		t.Fatalf("Unexpected JSON %s, %v", data, err)
+	}
+	var back []MatrixPos
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back[0] != NewMatrixPos(2, 3, 10) || back[1].Valid() {
+		t.Errorf("Unexpected round trip %v", back)
+	}
+
+	text, _ := NewMatrixPos(4, 5, 10).MarshalText()
+	var mp MatrixPos
+	if err := mp.UnmarshalText(text); err != nil || mp != NewMatrixPos(4, 5, 10) {
+		t.Errorf("Unexpected text round trip %s to %v, %v", text, mp, err)
+	}
+	for _, bad := range []string{`{"r":1}`, `[2,3]`, `[2,10,10]`} {
+		if err := mp.UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+	for _, bad := range []string{`[2, 3]`, `[2, 10, 10]`} {
+		if err := mp.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+
+	// map keys go through the text form
+	keyed, err := json.Marshal(map[MatrixPos]int{NewMatrixPos(1, 2, 7): 3})
+	if err != nil || string(keyed) != `{"[1, 2, 7]":3}` {
+		t.Fatalf("Unexpected JSON %s, %v", keyed, err)
+	}
+	var keys map[MatrixPos]int
+	if err := json.Unmarshal(keyed, &keys); err != nil || keys[NewMatrixPos(1, 2, 7)] != 3 {
+		t.Errorf("Unexpected map round trip %v, %v", keys, err)
+	}
+}
+
+func TestPolygonJSONAndText(t *testing.T) {
+	m, _ := ParseASCII(`
+		.......
+		.#####.
+		.#...#.
+		.#####.
+		.......
+	`)
+	polygons, _ := m.ExtractAllPolygons()
+	p := polygons[0]
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Polygon
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON.Outer, p.Outer) || len(fromJSON.Holes) != len(p.Holes) {
+		t.Errorf("Expected the JSON round trip to keep strides\n%s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"outer":[[0,0],[0,3]]}`), &fromJSON); err == nil {
+		t.Error("Expected an error for a polygon without its stride")
+	}
+
+	text, _ := p.MarshalText()
+	var fromText Polygon
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromText.Outer) != len(p.Outer) || len(fromText.Holes) != len(p.Holes) || fromText.Area2() != p.Area2() {
+		t.Errorf("Unexpected text round trip %s", text)
+	}
+	if err := fromText.UnmarshalText([]byte("POLYGON ((1 x, 2 2))")); err == nil {
+		t.Error("Expected an error for a point off the grid")
+	}
+}
+
+func TestMatrixBoundsJSON(t *testing.T) {
+	m := NewMatrixBitSet(10, 8)
+	bounds := NewRectBounds(m, 1, 2, 5, 7)
+	data, _ := json.Marshal(bounds)
+	var back MatrixBounds
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.MinR != 1 || back.MinC != 2 || back.MaxR != 5 || back.MaxC != 7 || back.M.C != 10 || back.M.R != 8 {
+		t.Errorf("Unexpected round trip %+v from %s", back, data)
+	}
+	if !back.NInside(m.index(3, 4)) || back.NInside(m.index(0, 0)) {
+		t.Error("Expected the vertexes to come back")
+	}
+
+	text, _ := bounds.MarshalText()
+	var fromText MatrixBounds
+	if err := fromText.UnmarshalText(text); err != nil || string(text) != "[1, 2]-[5, 7]" || fromText.Area() != bounds.Area() {
+		t.Errorf("Unexpected text round trip %s, %v", text, err)
+	}
+}
+
+func TestMatrixBitSetJSON(t *testing.T) {
+	rnd := rand.New(rand.NewSource(17))
+	for trial := 0; trial < 20; trial++ {
+		m := randomMatrix(rnd, uint(1+rnd.Intn(70)), uint(1+rnd.Intn(9)), 0.3)
+		for _, marshal := range []func() ([]byte, error){m.MarshalJSON, m.MarshalJSONRLE} {
+			data, err := marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var back MatrixBitSet
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			if !sameBits(&back, m) {
+				t.Errorf("Round trip differs for %s", data)
+			}
+		}
+	}
+
+	m, _ := ParseASCII("#..\n.##")
+	data, _ := m.MarshalJSONRLE()
+	if string(data) != `{"rows":2,"cols":3,"encoding":"rle","runs":[0,1,3,2]}` {
+		t.Errorf("Unexpected RLE %s", data)
+	}
+	for _, bad := range []string{
+		`{"rows":2,"cols":3,"encoding":"rle","runs":[0,1,3]}`,
+		`{"rows":2,"cols":3,"encoding":"base64","data":"AAAA"}`,
+		`{"rows":2,"cols":3,"encoding":"zip"}`,
+		// rows x cols wraps to 0
+		`{"rows":4294967296,"cols":4294967296,"encoding":"base64","data":""}`,
+		`{"rows":4294967296,"cols":4294967296,"encoding":"rle","runs":[]}`,
+		// too big to allocate, or more than the data holds
+		`{"rows":67108864,"cols":67108864}`,
+		`{"rows":65536,"cols":65536,"encoding":"base64","data":""}`,
+		`{"rows":65536,"cols":65536,"encoding":"rle","runs":[5]}`,
+	} {
+		var back MatrixBitSet
+		if err := json.Unmarshal([]byte(bad), &back); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+
+	text, _ := m.MarshalText()
+	var fromText MatrixBitSet
+	if err := fromText.UnmarshalText(text); err != nil || !sameBits(&fromText, m) {
+		t.Errorf("Unexpected text round trip %s, %v", text, err)
+	}
+}
@@ -0,0 +1,206 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Similarity between two masks of the same size, for scoring a
+// segmentation against its ground truth. A ratio with nothing to count,
+// like the IoU of two empty masks, is 1 since the masks agree there
+
+// Pixel counts of a predicted mask against the truth
+type Confusion struct {
+	TruePositive  uint
+	FalsePositive uint
+	FalseNegative uint
+	TrueNegative  uint
+}
+
+// Counts a word at a time, m is the prediction
+func (m *MatrixBitSet) Compare(truth *MatrixBitSet) (Confusion, error) {
+	if m.R != truth.R || m.C != truth.C {
+		return Confusion{}, fmt.Errorf("can't compare %d x %d with %d x %d", m.R, m.C, truth.R, truth.C)
+	}
+	var cf Confusion
+	total := m.R * m.C
+	for w := range m.B {
+		// defensive, B is exported so the padding past the last pixel may be set
+		mask := rowMask(total, w)
+		predicted, actual := m.B[w]&mask, truth.B[w]&mask
+		cf.TruePositive += uint(bits.OnesCount64(predicted & actual))
+		cf.FalsePositive += uint(bits.OnesCount64(predicted &^ actual))
+		cf.FalseNegative += uint(bits.OnesCount64(actual &^ predicted))
+	}
+	cf.TrueNegative = total - cf.TruePositive - cf.FalsePositive - cf.FalseNegative
+	return cf, nil
+}
+
+// Intersection over union, the Jaccard index
+func (cf Confusion) IoU() float64 {
+	return ratio(cf.TruePositive, cf.TruePositive+cf.FalsePositive+cf.FalseNegative)
+}
+
+func (cf Confusion) Dice() float64 {
+	return ratio(2*cf.TruePositive, 2*cf.TruePositive+cf.FalsePositive+cf.FalseNegative)
+}
+
+// Pixels that differ
+func (cf Confusion) Hamming() uint {
+	return cf.FalsePositive + cf.FalseNegative
+}
+
+func (cf Confusion) Precision() float64 {
+	return ratio(cf.TruePositive, cf.TruePositive+cf.FalsePositive)
+}
+
+func (cf Confusion) Recall() float64 {
+	return ratio(cf.TruePositive, cf.TruePositive+cf.FalseNegative)
+}
+
+// Same as Dice, the harmonic mean of Precision and Recall
+func (cf Confusion) F1() float64 {
+	return cf.Dice()
+}
+
+func ratio(num, den uint) float64 {
+	if den == 0 {
+		return 1
+	}
+	return float64(num) / float64(den)
+}
+
+func (m *MatrixBitSet) IoU(other *MatrixBitSet) (float64, error) {
+	cf, err := m.Compare(other)
+	return cf.IoU(), err
+}
+
+func (m *MatrixBitSet) Dice(other *MatrixBitSet) (float64, error) {
+	cf, err := m.Compare(other)
+	return cf.Dice(), err
+}
+
+func (m *MatrixBitSet) Hamming(other *MatrixBitSet) (uint, error) {
+	cf, err := m.Compare(other)
+	return cf.Hamming(), err
+}
+
+// Greatest distance from a border pixel of either mask to the nearest
+// border pixel of the other, borders as ExtractBorders finds them
+// 0 when neither has a border, +Inf when only one does
+func (m *MatrixBitSet) Hausdorff(other *MatrixBitSet) (float64, error) {
+	forward, backward, err := m.surfaceDistances(other)
+	if err != nil {
+		return 0, err
+	}
+	worst := 0.0
+	for _, d := range append(forward, backward...) {
+		worst = math.Max(worst, d)
+	}
+	return worst, nil
+}
+
+// Mean distance from each border pixel of either mask to the nearest
+// border pixel of the other, counting both ways together
+func (m *MatrixBitSet) AverageSurfaceDistance(other *MatrixBitSet) (float64, error) {
+	forward, backward, err := m.surfaceDistances(other)
+	if err != nil {
+		return 0, err
+	}
+	all := append(forward, backward...)
+	if len(all) == 0 {
+		return 0, nil
+	}
+	sum := 0.0
+	for _, d := range all {
+		sum += d
+	}
+	return sum / float64(len(all)), nil
+}
+
+// For every border pixel of m the distance to other's border, then the reverse
+func (m *MatrixBitSet) surfaceDistances(other *MatrixBitSet) ([]float64, []float64, error) {
+	if m.R != other.R || m.C != other.C {
+		return nil, nil, fmt.Errorf("can't compare %d x %d with %d x %d", m.R, m.C, other.R, other.C)
+	}
+	ours, _ := m.ExtractBorders()
+	theirs, _ := other.ExtractBorders()
+	return nearestDistances(ours, m.borderField(theirs)), nearestDistances(theirs, m.borderField(ours)), nil
+}
+
+func nearestDistances(points []MatrixPos, field []float64) []float64 {
+	distances := make([]float64, len(points))
+	for i, mp := range points {
+		distances[i] = math.Sqrt(field[mp.N()])
+	}
+	return distances
+}
+
+// Squared distance from every pixel to the nearest of points, an exact
+// Euclidean distance transform done a col then a row at a time
+// Felzenszwalb and Huttenlocher, Theory of Computing 2012
+func (m *MatrixBitSet) borderField(points []MatrixPos) []float64 {
+	field := make([]float64, m.R*m.C)
+	for i := range field {
+		field[i] = math.Inf(1)
+	}
+	for _, mp := range points {
+		field[mp.N()] = 0
+	}
+	line := make([]float64, maxUint(m.R, m.C))
+	for c := uint(0); c < m.C; c++ {
+		for r := uint(0); r < m.R; r++ {
+			line[r] = field[m.index(r, c)]
+		}
+		squaredDistance1D(line[:m.R])
+		for r := uint(0); r < m.R; r++ {
+			field[m.index(r, c)] = line[r]
+		}
+	}
+	for r := uint(0); r < m.R; r++ {
+		squaredDistance1D(field[m.index(r, 0):m.index(r+1, 0)])
+	}
+	return field
+}
+
+// In place, f[q] becomes min over p of (q - p)² + f[p]
+// by the lower envelope of the parabolas rooted at each p
+func squaredDistance1D(f []float64) {
+	n := len(f)
+	roots := make([]int, 0, n)
+	bounds := make([]float64, 0, n+1)
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for len(roots) > 0 {
+			p := roots[len(roots)-1]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s > bounds[len(bounds)-1] {
+				roots = append(roots, q)
+				bounds = append(bounds, s)
+				break
+			}
+			roots = roots[:len(roots)-1]
+			bounds = bounds[:len(bounds)-1]
+		}
+		if len(roots) == 0 {
+			roots = append(roots, q)
+			bounds = append(bounds, math.Inf(-1))
+		}
+	}
+	if len(roots) == 0 {
+		return
+	}
+	values := make([]float64, n)
+	k := 0
+	for q := 0; q < n; q++ {
+		for k+1 < len(roots) && bounds[k+1] < float64(q) {
+			k++
+		}
+		p := roots[k]
+		values[q] = float64((q-p)*(q-p)) + f[p]
+	}
+	copy(f, values)
+}
@@ -0,0 +1,115 @@
+package matrixbitset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	predicted := shapeFromRows([]string{
+		"##..",
+		"##..",
+		"....",
+	})
+	truth := shapeFromRows([]string{
+		".##.",
+		".##.",
+		"....",
+	})
+	cf, err := predicted.Compare(truth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf != (Confusion{TruePositive: 2, FalsePositive: 2, FalseNegative: 2, TrueNegative: 6}) {
+		t.Errorf("Unexpected confusion %+v", cf)
+	}
+	if cf.IoU() != 2.0/6 || cf.Dice() != 0.5 || cf.Hamming() != 4 {
+		t.Errorf("Expected IoU 1/3, Dice 1/2, Hamming 4, received %v, %v, %d", cf.IoU(), cf.Dice(), cf.Hamming())
+	}
+	if cf.Precision() != 0.5 || cf.Recall() != 0.5 || cf.F1() != 0.5 {
+		t.Errorf("Expected 1/2 all round, received %v, %v, %v", cf.Precision(), cf.Recall(), cf.F1())
+	}
+
+	empty := NewMatrixBitSet(4, 3)
+	if iou, _ := empty.IoU(NewMatrixBitSet(4, 3)); iou != 1 {
+		t.Errorf("Expected two empty masks to agree, received %v", iou)
+	}
+	if _, err := empty.Dice(NewMatrixBitSet(3, 4)); err == nil {
+		t.Error("Expected an error comparing different sizes")
+	}
+
+	// bits Invert leaves on past the last pixel aren't counted
+	if cf, _ := empty.Invert().Compare(NewMatrixBitSet(4, 3).Invert()); cf.TruePositive != 12 || cf.TrueNegative != 0 {
+		t.Errorf("Unexpected confusion after Invert %+v", cf)
+	}
+}
+
+func TestHammingAgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+	for trial := 0; trial < 20; trial++ {
+		w, h := uint(1+rnd.Intn(90)), uint(1+rnd.Intn(9))
+		a, b := randomMatrix(rnd, w, h, 0.5), randomMatrix(rnd, w, h, 0.3)
+		expected := uint(0)
+		for i := uint(0); i < w*h; i++ {
+			if a.TestN(i) != b.TestN(i) {
+				expected++
+			}
+		}
+		if received, _ := a.Hamming(b); received != expected {
+			t.Errorf("Expected %d, received %d", expected, received)
+		}
+	}
+}
+
+func bruteSurfaceDistances(a, b *MatrixBitSet) []float64 {
+	ours, _ := a.ExtractBorders()
+	theirs, _ := b.ExtractBorders()
+	nearest := func(from, to []MatrixPos) []float64 {
+		distances := make([]float64, 0, len(from))
+		for _, p := range from {
+			best := math.Inf(1)
+			for _, q := range to {
+				best = math.Min(best, math.Hypot(float64(p.r)-float64(q.r), float64(p.c)-float64(q.c)))
+			}
+			distances = append(distances, best)
+		}
+		return distances
+	}
+	return append(nearest(ours, theirs), nearest(theirs, ours)...)
+}
+
+func TestSurfaceDistancesAgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(9))
+	for trial := 0; trial < 40; trial++ {
+		w, h := uint(2+rnd.Intn(20)), uint(2+rnd.Intn(20))
+		a, b := randomMatrix(rnd, w, h, 0.3), randomMatrix(rnd, w, h, 0.1)
+		distances := bruteSurfaceDistances(a, b)
+		worst, sum := 0.0, 0.0
+		for _, d := range distances {
+			worst = math.Max(worst, d)
+			sum += d
+		}
+		if hausdorff, _ := a.Hausdorff(b); math.Abs(hausdorff-worst) > 1e-9 {
+			t.Errorf("Expected Hausdorff %v, received %v", worst, hausdorff)
+		}
+		average, _ := a.AverageSurfaceDistance(b)
+		if len(distances) > 0 && math.Abs(average-sum/float64(len(distances))) > 1e-9 {
+			t.Errorf("Expected average %v, received %v", sum/float64(len(distances)), average)
+		}
+	}
+}
+
+func TestSurfaceDistancesEmpty(t *testing.T) {
+	empty, one := NewMatrixBitSet(5, 5), NewMatrixBitSet(5, 5)
+	one.Set(2, 2)
+	if d, _ := empty.Hausdorff(NewMatrixBitSet(5, 5)); d != 0 {
+		t.Errorf("Expected 0 between empty masks, received %v", d)
+	}
+	if d, _ := empty.Hausdorff(one); !math.IsInf(d, 1) {
+		t.Errorf("Expected +Inf against an empty mask, received %v", d)
+	}
+	if d, _ := one.AverageSurfaceDistance(one); d != 0 {
+		t.Errorf("Expected 0 against itself, received %v", d)
+	}
+}
@@ -0,0 +1,184 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// NumPy .npy files, either an (R, C) bool array or the (R, ceil(C/8)) uint8
+// array numpy.packbits(mask, axis=1) makes. Files are written in C order
+// and read in either C or Fortran order
+
+// Which end of a packed byte holds the first pixel, numpy's bitorder
+type NPYBitOrder int
+
+const (
+	// The first pixel in the high bit, numpy's default
+	NPYBitOrderBig NPYBitOrder = iota
+	NPYBitOrderLittle
+)
+
+const npyMagic = "\x93NUMPY"
+
+// Format version 1.0 while the header fits, otherwise 2.0, padded so
+// the data starts 64 byte aligned like numpy.save
+func writeNPY(w io.Writer, descr string, rows, cols uint, data []byte) error {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }", descr, rows, cols)
+	prefix := len(npyMagic) + 2 + 2
+	if len(header)+prefix+1 > 0xffff {
+		prefix += 2
+	}
+	header += string(bytes.Repeat([]byte(" "), (64-(prefix+len(header)+1)%64)%64)) + "\n"
+
+	var b bytes.Buffer
+	b.WriteString(npyMagic)
+	if prefix == len(npyMagic)+4 {
+		b.Write([]byte{1, 0})
+		binary.Write(&b, binary.LittleEndian, uint16(len(header)))
+	} else {
+		b.Write([]byte{2, 0})
+		binary.Write(&b, binary.LittleEndian, uint32(len(header)))
+	}
+	b.WriteString(header)
+	b.Write(data)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// An (R, C) bool array, a byte per pixel
+func (m *MatrixBitSet) WriteNPY(w io.Writer) error {
+	data := make([]byte, m.R*m.C)
+	for i, ok := m.nextSet(0); ok && i < m.R*m.C; i, ok = m.nextSet(i + 1) {
+		data[i] = 1
+	}
+	return writeNPY(w, "|b1", m.R, m.C, data)
+}
+
+// What numpy.packbits(mask, axis=1, bitorder=...) gives
+func (m *MatrixBitSet) WriteNPYPacked(w io.Writer, order NPYBitOrder) error {
+	return writeNPY(w, "|u1", m.R, (m.C+7)/8, m.packRows(order == NPYBitOrderBig))
+}
+
+type npyHeader struct {
+	descr   string
+	fortran bool
+	rows    uint
+	cols    uint
+}
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*['"]([^'"]+)['"]`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(\s*(\d+)\s*,\s*(\d+)\s*,?\s*\)`)
+)
+
+// The header and the data after it, which must hold rows x cols bytes
+func readNPY(r io.Reader) (*npyHeader, []byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < len(npyMagic)+4 || string(data[:len(npyMagic)]) != npyMagic {
+		return nil, nil, fmt.Errorf("npy: not a .npy file")
+	}
+	at := len(npyMagic) + 2
+	var size int
+	switch data[len(npyMagic)] {
+	case 1:
+		size = int(binary.LittleEndian.Uint16(data[at:]))
+		at += 2
+	case 2, 3:
+		if len(data) < at+4 {
+			return nil, nil, fmt.Errorf("npy: header cut short")
+		}
+		size = int(binary.LittleEndian.Uint32(data[at:]))
+		at += 4
+	default:
+		return nil, nil, fmt.Errorf("npy: unknown version %d", data[len(npyMagic)])
+	}
+	if len(data) < at+size {
+		return nil, nil, fmt.Errorf("npy: header cut short")
+	}
+	header := string(data[at : at+size])
+
+	descr, fortran, shape := npyDescr.FindStringSubmatch(header), npyFortran.FindStringSubmatch(header), npyShape.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return nil, nil, fmt.Errorf("npy: expected a 2 dimensional array, header %q", header)
+	}
+	rows, errR := strconv.ParseUint(shape[1], 10, 0)
+	cols, errC := strconv.ParseUint(shape[2], 10, 0)
+	if errR != nil || errC != nil {
+		return nil, nil, fmt.Errorf("npy: shape %q", shape[0])
+	}
+	nh := &npyHeader{descr: descr[1], fortran: fortran[1] == "True", rows: uint(rows), cols: uint(cols)}
+	if nh.rows != 0 && nh.cols > math.MaxUint/nh.rows {
+		return nil, nil, fmt.Errorf("npy: shape (%d, %d) overflows", rows, cols)
+	}
+	body := data[at+size:]
+	if uint(len(body)) < nh.rows*nh.cols {
+		return nil, nil, fmt.Errorf("npy: %d bytes for shape (%d, %d)", len(body), rows, cols)
+	}
+	body = body[:nh.rows*nh.cols]
+	if nh.fortran {
+		// col by col into row by row
+		ordered := make([]byte, len(body))
+		for c := uint(0); c < nh.cols; c++ {
+			for r := uint(0); r < nh.rows; r++ {
+				ordered[r*nh.cols+c] = body[c*nh.rows+r]
+			}
+		}
+		body = ordered
+	}
+	return nh, body, nil
+}
+
+// An (R, C) array of bool or any one byte integer, nonzero is set
+func ReadNPY(r io.Reader) (*MatrixBitSet, error) {
+	nh, body, err := readNPY(r)
+	if err != nil {
+		return nil, err
+	}
+	switch nh.descr {
+	case "|b1", "?", "|u1", "u1", "|i1", "i1":
+	default:
+		return nil, fmt.Errorf("npy: dtype %q isn't one byte per pixel", nh.descr)
+	}
+	m, err := NewMatrixBitSetChecked(nh.cols, nh.rows)
+	if err != nil {
+		return nil, fmt.Errorf("npy: %v", err)
+	}
+	for i, b := range body {
+		if b != 0 {
+			m.set(uint(i))
+		}
+	}
+	return m, nil
+}
+
+// A packbits uint8 array, cols is the unpacked width
+// which the file doesn't record, numpy.unpackbits(count=cols)
+func ReadNPYPacked(r io.Reader, cols uint, order NPYBitOrder) (*MatrixBitSet, error) {
+	nh, body, err := readNPY(r)
+	if err != nil {
+		return nil, err
+	}
+	if nh.descr != "|u1" && nh.descr != "u1" {
+		return nil, fmt.Errorf("npy: dtype %q isn't uint8", nh.descr)
+	}
+	// the file's width must match before cols is trusted with an allocation,
+	// readNPY has already checked the body holds every row
+	if nh.cols != (cols+7)/8 {
+		return nil, fmt.Errorf("npy: %d bytes a row can't hold %d cols", nh.cols, cols)
+	}
+	m, err := NewMatrixBitSetChecked(cols, nh.rows)
+	if err != nil {
+		return nil, fmt.Errorf("npy: %v", err)
+	}
+	m.setPackedRows(body, order == NPYBitOrderBig)
+	return m, nil
+}
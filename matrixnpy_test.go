@@ -0,0 +1,101 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// What numpy.save(f, numpy.array([[True, False, True], [False, True, True]])) writes
+func TestWriteNPYMatchesNumpy(t *testing.T) {
+	m, _ := ParseASCII("#.#\n.##")
+	header := "{'descr': '|b1', 'fortran_order': False, 'shape': (2, 3), }"
+	header += strings.Repeat(" ", 128-10-len(header)-1) + "\n"
+	expected := append([]byte("\x93NUMPY\x01\x00\x76\x00"+header), 1, 0, 1, 0, 1, 1)
+
+	var b bytes.Buffer
+	if err := m.WriteNPY(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("Expected\n%q\nreceived\n%q", expected, b.Bytes())
+	}
+
+	// numpy.packbits on the same rows, big then little
+	for _, tc := range []struct {
+		order NPYBitOrder
+		data  []byte
+	}{{NPYBitOrderBig, []byte{0xa0, 0x60}}, {NPYBitOrderLittle, []byte{0x05, 0x06}}} {
+		b.Reset()
+		m.WriteNPYPacked(&b, tc.order)
+		if received := b.Bytes()[128:]; !bytes.Equal(received, tc.data) || !bytes.Contains(b.Bytes(), []byte("'shape': (2, 1)")) {
+			t.Errorf("Bit order %d: expected %x, received %x", tc.order, tc.data, received)
+		}
+	}
+}
+
+func TestNPYRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(41))
+	for trial := 0; trial < 20; trial++ {
+		m := randomMatrix(rnd, uint(1+rnd.Intn(100)), uint(1+rnd.Intn(20)), 0.4)
+		var b bytes.Buffer
+		m.WriteNPY(&b)
+		if back, err := ReadNPY(&b); err != nil || !sameBits(back, m) {
+			t.Fatalf("Bool round trip differs, %v", err)
+		}
+		for _, order := range []NPYBitOrder{NPYBitOrderBig, NPYBitOrderLittle} {
+			b.Reset()
+			m.WriteNPYPacked(&b, order)
+			if back, err := ReadNPYPacked(&b, m.C, order); err != nil || !sameBits(back, m) {
+				t.Fatalf("Packed round trip differs for bit order %d, %v", order, err)
+			}
+		}
+	}
+}
+
+func npyFile(header string, data ...byte) *bytes.Reader {
+	return bytes.NewReader(append([]byte("\x93NUMPY\x01\x00"+string([]byte{byte(len(header)), 0})+header), data...))
+}
+
+func TestReadNPYFortranOrder(t *testing.T) {
+	// the 2 x 3 from above col by col, as uint8
+	f := npyFile("{'descr': '|u1', 'fortran_order': True, 'shape': (2, 3), }\n", 1, 0, 0, 1, 1, 1)
+	m, err := ReadNPY(f)
+	expected, _ := ParseASCII("#.#\n.##")
+	if err != nil || !sameBits(m, expected) {
+		t.Errorf("Unexpected matrix %v\n%v", err, m)
+	}
+
+	// packed, 2 rows of 10 cols, 2 bytes a row stored col by col
+	f = npyFile("{'descr': '|u1', 'fortran_order': True, 'shape': (2, 2), }\n", 0x80, 0x01, 0x40, 0xc0)
+	m, err = ReadNPYPacked(f, 10, NPYBitOrderBig)
+	expected, _ = ParseASCII("#........#\n.......###")
+	if err != nil || !sameBits(m, expected) {
+		t.Errorf("Unexpected packed matrix %v\n%v", err, m)
+	}
+}
+
+func TestReadNPYErrors(t *testing.T) {
+	for name, f := range map[string]*bytes.Reader{
+		"magic": bytes.NewReader([]byte("NUMPY")),
+		"dtype": npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (1, 1), }\n", 0, 0, 0, 0, 0, 0, 0, 0),
+		"1d":    npyFile("{'descr': '|b1', 'fortran_order': False, 'shape': (3,), }\n", 1, 1, 1),
+		"short": npyFile("{'descr': '|b1', 'fortran_order': False, 'shape': (2, 2), }\n", 1, 1, 1),
+		"wraps": npyFile("{'descr': '|b1', 'fortran_order': False, 'shape': (8589934592, 2147483648), }\n"),
+		"empty": npyFile("{'descr': '|b1', 'fortran_order': False, 'shape': (0, 3), }\n"),
+	} {
+		if _, err := ReadNPY(f); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	f := npyFile("{'descr': '|u1', 'fortran_order': False, 'shape': (1, 2), }\n", 0, 0)
+	if _, err := ReadNPYPacked(f, 20, NPYBitOrderBig); err == nil {
+		t.Error("Expected an error for cols the rows can't hold")
+	}
+	// refused before a 2^31 col matrix is allocated
+	f = npyFile("{'descr': '|u1', 'fortran_order': False, 'shape': (1, 2), }\n", 0, 0)
+	if _, err := ReadNPYPacked(f, 1<<31, NPYBitOrderBig); err == nil {
+		t.Error("Expected an error for cols far past the rows")
+	}
+}
@@ -0,0 +1,230 @@
+package matrixbitset
+
+import (
+	"context"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Runs the bulk ops split into bands over a bounded pool of goroutines
+// Word ops (Count, Invert, And, Or, Xor, AndNot) split B on word
+// boundaries so no two workers touch the same word, row ops split on
+// row boundaries and only read.
+// Results are merged in band order so they match the serial versions exactly
+type ParallelRunner struct {
+	M       *MatrixBitSet
+	Workers int
+}
+
+// workers <= 0 uses GOMAXPROCS
+func (m *MatrixBitSet) Parallel(workers int) *ParallelRunner {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelRunner{M: m, Workers: workers}
+}
+
+func (pr *ParallelRunner) Count(ctx context.Context) (uint, error) {
+	bands := pr.bands(uint(len(pr.M.B)))
+	counts := make([]int, len(bands))
+	err := pr.run(ctx, len(bands), func(b int) {
+		for _, w := range pr.M.B[bands[b][0]:bands[b][1]] {
+			counts[b] += bits.OnesCount64(w)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return uint(total), nil
+}
+
+// Flip every bit in place, the padding past the last bit stays clear
+// on cancellation some bands may already be flipped
+func (pr *ParallelRunner) Invert(ctx context.Context) error {
+	bands := pr.bands(uint(len(pr.M.B)))
+	total := pr.M.R * pr.M.C
+	return pr.run(ctx, len(bands), func(b int) {
+		for x := bands[b][0]; x < bands[b][1]; x++ {
+			pr.M.B[x] = ^pr.M.B[x] & rowMask(total, int(x))
+		}
+	})
+}
+
+func (pr *ParallelRunner) And(ctx context.Context, other *MatrixBitSet) error {
+	return pr.wordOp(ctx, other, func(w, o uint64) uint64 { return w & o })
+}
+
+func (pr *ParallelRunner) Or(ctx context.Context, other *MatrixBitSet) error {
+	return pr.wordOp(ctx, other, func(w, o uint64) uint64 { return w | o })
+}
+
+func (pr *ParallelRunner) Xor(ctx context.Context, other *MatrixBitSet) error {
+	return pr.wordOp(ctx, other, func(w, o uint64) uint64 { return w ^ o })
+}
+
+func (pr *ParallelRunner) AndNot(ctx context.Context, other *MatrixBitSet) error {
+	return pr.wordOp(ctx, other, func(w, o uint64) uint64 { return w &^ o })
+}
+
+// Combines other into M a band of words at a time, both the same size
+// on cancellation some bands may already be combined
+func (pr *ParallelRunner) wordOp(ctx context.Context, other *MatrixBitSet, op func(w, o uint64) uint64) error {
+	pr.M.panicOtherSize(other)
+	bands := pr.bands(uint(len(pr.M.B)))
+	return pr.run(ctx, len(bands), func(b int) {
+		for x := bands[b][0]; x < bands[b][1]; x++ {
+			pr.M.B[x] = op(pr.M.B[x], other.B[x])
+		}
+	})
+}
+
+// The first and last bits are found as BoundsOfSets finds them,
+// the row scans for the min and max cols are banded
+func (pr *ParallelRunner) BoundsOfSets(ctx context.Context) (*MatrixBounds, bool, error) {
+	m := pr.M
+	var err error
+	findMinC := func(bounds *MatrixBounds) (uint, uint, bool) {
+		partial, e := pr.colScans(ctx, bounds.minCRows, m.minCBetween)
+		if err = e; err != nil {
+			return 0, 0, false
+		}
+		minC, n, good := m.C, uint(0), false
+		for _, p := range partial {
+			// the topmost of equal cols, like minCBetween
+			if p.good && p.c < minC {
+				minC, n, good = p.c, p.n, true
+			}
+		}
+		return minC, n, good
+	}
+	findMaxC := func(bounds *MatrixBounds) (uint, uint, bool) {
+		partial, e := pr.colScans(ctx, bounds.maxCRows, m.maxCBetween)
+		if err = e; err != nil {
+			return 0, 0, false
+		}
+		maxC, n, good := uint(0), uint(0), false
+		for _, p := range partial {
+			// the topmost of equal cols, like maxCBetween
+			if p.good && (!good || p.c > maxC) {
+				maxC, n, good = p.c, p.n, true
+			}
+		}
+		return maxC, n, good
+	}
+	bounds, ok := m.boundsOfSets(findMinC, findMaxC)
+	if err != nil {
+		return nil, false, err
+	}
+	return bounds, ok, nil
+}
+
+type colScan struct {
+	c, n uint
+	good bool
+}
+
+// Runs scan over bands of the rows given, in band order
+func (pr *ParallelRunner) colScans(ctx context.Context, rows func() (uint, uint),
+	scan func(r0, r1 uint) (uint, uint, bool)) ([]colScan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r0, r1 := rows()
+	if r1 <= r0 {
+		return nil, nil
+	}
+	bands := pr.bands(r1 - r0)
+	partial := make([]colScan, len(bands))
+	err := pr.run(ctx, len(bands), func(b int) {
+		partial[b].c, partial[b].n, partial[b].good = scan(r0+bands[b][0], r0+bands[b][1])
+	})
+	return partial, err
+}
+
+func (pr *ParallelRunner) ExtractBorders(ctx context.Context) ([]MatrixPos, bool, error) {
+	m := pr.M
+	bands := pr.bands(m.R)
+	partial := make([][]MatrixPos, len(bands))
+	err := pr.run(ctx, len(bands), func(b int) {
+		partial[b] = m.bordersBetween(bands[b][0], bands[b][1])
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	total := 0
+	for _, p := range partial {
+		total += len(p)
+	}
+	borders := make([]MatrixPos, 0, total)
+	for _, p := range partial {
+		borders = append(borders, p...)
+	}
+	return borders, len(borders) != 0, nil
+}
+
+// Non interior positions for rows [r0, r1)
+func (m *MatrixBitSet) bordersBetween(r0, r1 uint) []MatrixPos {
+	borders := make([]MatrixPos, 0, 512)
+	end := m.index(r1, 0)
+	for i, e := m.nextSet(m.index(r0, 0)); e && i < end; i, e = m.nextSet(i + 1) {
+		if !m.internalN(i) {
+			borders = append(borders, m.NewPos(i))
+		}
+	}
+	return borders
+}
+
+// Splits [0, n) into [start, end) pairs, a few per worker so
+// an uneven band doesn't leave the rest of the pool idle
+func (pr *ParallelRunner) bands(n uint) [][2]uint {
+	parts := uint(pr.Workers) * 4
+	if parts > n {
+		parts = n
+	}
+	bands := make([][2]uint, 0, parts)
+	for p := uint(0); p < parts; p++ {
+		bands = append(bands, [2]uint{n * p / parts, n * (p + 1) / parts})
+	}
+	return bands
+}
+
+// Hands band indexes to at most Workers goroutines
+// stops handing out bands once ctx is done
+func (pr *ParallelRunner) run(ctx context.Context, bands int, fn func(band int)) error {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	workers := pr.Workers
+	if workers > bands {
+		workers = bands
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range work {
+				fn(b)
+			}
+		}()
+	}
+	var err error
+feed:
+	for b := 0; b < bands; b++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		case work <- b:
+		}
+	}
+	close(work)
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,134 @@
+package matrixbitset
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParallelMatchesSerial(t *testing.T) {
+	fixtures := []string{`
+		..............................
+		.....#####..........##........
+		...#######..........####......
+		.....###......................
+		..........................###.
+		......##......................`, `
+		....##....
+		..######..`, `
+		.........
+		...##....
+		.#.......
+		........#`, `
+		..###..`, `
+		......
+		......`,
+	}
+	ctx := context.Background()
+	for _, fixture := range fixtures {
+		m, err := ParseASCII(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{1, 3, 16} {
+			pr := m.Parallel(workers)
+			if cnt, err := pr.Count(ctx); err != nil || cnt != m.Count() {
+				t.Errorf("%d workers: expected count %d, received %d %v", workers, m.Count(), cnt, err)
+			}
+
+			expected, expectedOk := m.BoundsOfSets()
+			bounds, ok, err := pr.BoundsOfSets(ctx)
+			if err != nil || ok != expectedOk {
+				t.Fatalf("%d workers: expected BoundsOfSets %v, received %v %v\n%s", workers, expectedOk, ok, err, m.FormatASCII())
+			}
+			if !reflect.DeepEqual(expected, bounds) {
+				t.Errorf("%d workers: expected bounds %+v, received %+v\n%s", workers, expected, bounds, m.FormatASCII())
+			}
+
+			serial, _ := m.ExtractBorders()
+			borders, _, err := pr.ExtractBorders(ctx)
+			if err != nil || !reflect.DeepEqual(serial, borders) {
+				t.Errorf("%d workers: borders differ from serial (%d vs %d) %v", workers, len(serial), len(borders), err)
+			}
+
+			inverted, _ := ParseASCII(fixture)
+			inverted.Invert()
+			if err := pr.Invert(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(inverted.B, m.B) {
+				t.Errorf("%d workers: Invert differs from serial\n%s", workers, m.FormatASCII())
+			}
+			m.Invert()
+		}
+	}
+}
+
+func TestParallelSetAlgebra(t *testing.T) {
+	a, _ := ParseASCII(`
+		##########..........##########
+		..........##########..........
+		#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.
+		.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#
+		###############...............`)
+	b, _ := ParseASCII(`
+		#####.....#####.....#####.....
+		#####.....#####.....#####.....
+		##############################
+		..............................
+		.......#################......`)
+	ops := []struct {
+		name     string
+		serial   func(m *MatrixBitSet) *MatrixBitSet
+		parallel func(pr *ParallelRunner) error
+	}{
+		{"And", func(m *MatrixBitSet) *MatrixBitSet { return m.And(b) },
+			func(pr *ParallelRunner) error { return pr.And(context.Background(), b) }},
+		{"Or", func(m *MatrixBitSet) *MatrixBitSet { return m.Or(b) },
+			func(pr *ParallelRunner) error { return pr.Or(context.Background(), b) }},
+		{"Xor", func(m *MatrixBitSet) *MatrixBitSet { return m.Xor(b) },
+			func(pr *ParallelRunner) error { return pr.Xor(context.Background(), b) }},
+		{"AndNot", func(m *MatrixBitSet) *MatrixBitSet { return m.AndNot(b) },
+			func(pr *ParallelRunner) error { return pr.AndNot(context.Background(), b) }},
+	}
+	fresh := func() *MatrixBitSet {
+		return &MatrixBitSet{B: append([]uint64(nil), a.B...), R: a.R, C: a.C}
+	}
+	for _, op := range ops {
+		expected := op.serial(fresh())
+		for _, workers := range []int{1, 2, 16} {
+			m := fresh()
+			if err := op.parallel(m.Parallel(workers)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected.B, m.B) {
+				t.Errorf("%s with %d workers: expected\n%s\nreceived\n%s", op.name, workers, expected.FormatASCII(), m.FormatASCII())
+			}
+		}
+	}
+
+	expected, _ := ParseASCII(`
+		#####...............#####.....
+		..........#####...............
+		#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.
+		..............................
+		.......########...............`)
+	if and := fresh().And(b); !reflect.DeepEqual(expected.B, and.B) {
+		t.Errorf("Expected And\n%s\nreceived\n%s", expected.FormatASCII(), and.FormatASCII())
+	}
+}
+
+func TestParallelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m, _ := ParseASCII(`
+		.##.
+		####
+		.##.`)
+	if _, err := m.Parallel(4).Count(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, received %v", err)
+	}
+	if _, _, err := m.Parallel(4).BoundsOfSets(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from BoundsOfSets, received %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+package matrixbitset
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Shortest paths between cells of a mask used as a walkable map
+// Set cells are walkable, WithWalkable(false) walks the clear ones instead
+
+type PathAlgorithm int
+
+const (
+	// Fewest steps, a diagonal step counts the same as a straight one
+	BreadthFirst PathAlgorithm = iota
+	// Cheapest path, straight steps cost 1 and diagonal ones √2
+	Dijkstra
+	// Dijkstra guided towards the goal by the Heuristic
+	AStar
+	// A* expanding only jump points, Harabor and Grastien AAAI 2011
+	// Needs EightConnected with CornerCutNever, otherwise runs AStar
+	JumpPoint
+)
+
+type Connectivity int
+
+const (
+	FourConnected Connectivity = iota
+	EightConnected
+)
+
+// When a diagonal step may pass the corner of a blocked cell
+type CornerRule int
+
+const (
+	// Only when both cells beside the step are walkable
+	CornerCutNever CornerRule = iota
+	// When at least one of them is
+	CornerCutOne
+	// Always, even squeezing between two blocked cells
+	CornerCutAlways
+)
+
+// Manhattan overestimates diagonal steps, so with EightConnected
+// A* may then return a path that isn't the cheapest
+type Heuristic int
+
+const (
+	// Manhattan when FourConnected, Octile when EightConnected
+	DefaultHeuristic Heuristic = iota
+	Manhattan
+	Octile
+	Euclidean
+)
+
+type PathOption func(*pathOptions)
+
+type pathOptions struct {
+	algorithm    PathAlgorithm
+	connectivity Connectivity
+	corners      CornerRule
+	heuristic    Heuristic
+	walkSet      bool
+}
+
+func WithAlgorithm(algorithm PathAlgorithm) PathOption {
+	return func(po *pathOptions) {
+		po.algorithm = algorithm
+	}
+}
+
+func WithConnectivity(connectivity Connectivity) PathOption {
+	return func(po *pathOptions) {
+		po.connectivity = connectivity
+	}
+}
+
+func WithCornerRule(corners CornerRule) PathOption {
+	return func(po *pathOptions) {
+		po.corners = corners
+	}
+}
+
+func WithHeuristic(heuristic Heuristic) PathOption {
+	return func(po *pathOptions) {
+		po.heuristic = heuristic
+	}
+}
+
+// true walks the set cells, false the clear ones
+func WithWalkable(set bool) PathOption {
+	return func(po *pathOptions) {
+		po.walkSet = set
+	}
+}
+
+func newPathOptions(opts []PathOption) *pathOptions {
+	po := &pathOptions{algorithm: AStar, connectivity: FourConnected, corners: CornerCutNever, walkSet: true}
+	for _, opt := range opts {
+		opt(po)
+	}
+	if po.heuristic == DefaultHeuristic {
+		po.heuristic = Manhattan
+		if po.connectivity == EightConnected {
+			po.heuristic = Octile
+		}
+	}
+	if po.algorithm == JumpPoint && (po.connectivity != EightConnected || po.corners != CornerCutNever) {
+		po.algorithm = AStar
+	}
+	return po
+}
+
+// The path from one cell to the other with both ends included, and the
+// cells the search reached on the way. false when either end isn't walkable
+// or there's no path, the reached cells are then all those connected to from,
+// except for JumpPoint which only marks the jump points it expanded
+func (m *MatrixBitSet) FindPath(from, to MatrixPos, opts ...PathOption) ([]MatrixPos, *MatrixBitSet, bool) {
+	ps := m.newPathSearch(opts)
+	if !ps.walkablePos(from) || !ps.walkablePos(to) {
+		return nil, ps.reached, false
+	}
+	start := m.index(from.r, from.c)
+	ps.goal = m.index(to.r, to.c)
+
+	found := false
+	switch ps.po.algorithm {
+	case BreadthFirst:
+		found = ps.breadthFirst(start)
+	case Dijkstra:
+		found = ps.bestFirst(start, func(uint) float64 { return 0 }, ps.neighbors)
+	case AStar:
+		found = ps.bestFirst(start, ps.estimate, ps.neighbors)
+	case JumpPoint:
+		found = ps.bestFirst(start, ps.estimate, ps.jumpSuccessors)
+	}
+	if !found {
+		return nil, ps.reached, false
+	}
+	return ps.path(start), ps.reached, true
+}
+
+// Every cell connected to from, empty when from isn't walkable
+func (m *MatrixBitSet) Reachable(from MatrixPos, opts ...PathOption) *MatrixBitSet {
+	ps := m.newPathSearch(opts)
+	// only the reached cells are wanted, not the way to them
+	ps.parent = nil
+	if ps.walkablePos(from) {
+		ps.goal = ^uint(0)
+		ps.breadthFirst(m.index(from.r, from.c))
+	}
+	return ps.reached
+}
+
+// parent and cost only hold the cells the search has touched,
+// so a short path across a big matrix stays small
+type pathSearch struct {
+	m       *MatrixBitSet
+	po      *pathOptions
+	goal    uint
+	parent  map[uint]uint
+	cost    map[uint]float64
+	reached *MatrixBitSet
+}
+
+func (m *MatrixBitSet) newPathSearch(opts []PathOption) *pathSearch {
+	return &pathSearch{
+		m:       m,
+		po:      newPathOptions(opts),
+		parent:  make(map[uint]uint),
+		cost:    make(map[uint]float64),
+		reached: NewMatrixBitSet(m.C, m.R),
+	}
+}
+
+// Infinite until the search gets to n
+func (ps *pathSearch) costOf(n uint) float64 {
+	if g, ok := ps.cost[n]; ok {
+		return g
+	}
+	return math.Inf(1)
+}
+
+// Straight steps first, then diagonals
+var pathSteps = [8][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+func (ps *pathSearch) walkable(r, c int) bool {
+	if r < 0 || c < 0 || uint(r) >= ps.m.R || uint(c) >= ps.m.C {
+		return false
+	}
+	return ps.m.test(ps.m.index(uint(r), uint(c))) == ps.po.walkSet
+}
+
+func (ps *pathSearch) walkablePos(mp MatrixPos) bool {
+	return mp.Valid() && ps.walkable(int(mp.r), int(mp.c))
+}
+
+func (ps *pathSearch) diagonalAllowed(r, c, dr, dc int) bool {
+	open := 0
+	if ps.walkable(r+dr, c) {
+		open++
+	}
+	if ps.walkable(r, c+dc) {
+		open++
+	}
+	switch ps.po.corners {
+	case CornerCutAlways:
+		return true
+	case CornerCutOne:
+		return open > 0
+	}
+	return open == 2
+}
+
+func (ps *pathSearch) neighbors(n uint, fn func(next uint, step float64)) {
+	r, c := ps.m.asRC(n)
+	steps := pathSteps[:4]
+	if ps.po.connectivity == EightConnected {
+		steps = pathSteps[:]
+	}
+	for _, s := range steps {
+		nr, nc := int(r)+s[0], int(c)+s[1]
+		if !ps.walkable(nr, nc) {
+			continue
+		}
+		step := 1.0
+		if s[0] != 0 && s[1] != 0 {
+			if !ps.diagonalAllowed(int(r), int(c), s[0], s[1]) {
+				continue
+			}
+			step = math.Sqrt2
+		}
+		fn(ps.m.index(uint(nr), uint(nc)), step)
+	}
+}
+
+// Lower bound on the cost from n to the goal
+func (ps *pathSearch) estimate(n uint) float64 {
+	r, c := ps.m.asRC(n)
+	gr, gc := ps.m.asRC(ps.goal)
+	dr, dc := math.Abs(float64(r)-float64(gr)), math.Abs(float64(c)-float64(gc))
+	switch ps.po.heuristic {
+	case Manhattan:
+		return dr + dc
+	case Euclidean:
+		return math.Hypot(dr, dc)
+	}
+	return octile(dr, dc)
+}
+
+// Cost of the cheapest 8 connected path over open ground
+func octile(dr, dc float64) float64 {
+	return math.Max(dr, dc) + (math.Sqrt2-1)*math.Min(dr, dc)
+}
+
+func (ps *pathSearch) breadthFirst(start uint) bool {
+	ps.reached.set(start)
+	queue := []uint{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == ps.goal {
+			return true
+		}
+		ps.neighbors(n, func(next uint, _ float64) {
+			if ps.reached.test(next) {
+				return
+			}
+			ps.reached.set(next)
+			if ps.parent != nil {
+				ps.parent[next] = n
+			}
+			queue = append(queue, next)
+		})
+	}
+	return false
+}
+
+// Dijkstra when estimate is always 0, A* otherwise
+// expand calls back with each successor and the cost of getting there
+func (ps *pathSearch) bestFirst(start uint, estimate func(uint) float64, expand func(uint, func(uint, float64))) bool {
+	ps.cost[start] = 0
+	queue := &pathQueue{{n: start, f: estimate(start)}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(pathItem)
+		if ps.reached.test(item.n) {
+			continue
+		}
+		ps.reached.set(item.n)
+		if item.n == ps.goal {
+			return true
+		}
+		expand(item.n, func(next uint, step float64) {
+			g := ps.costOf(item.n) + step
+			if ps.reached.test(next) || g >= ps.costOf(next) {
+				return
+			}
+			ps.cost[next] = g
+			ps.parent[next] = item.n
+			heap.Push(queue, pathItem{n: next, g: g, f: g + estimate(next)})
+		})
+	}
+	return false
+}
+
+// Follows the parents back from the goal, filling in the straight
+// and diagonal runs between jump points
+func (ps *pathSearch) path(start uint) []MatrixPos {
+	chain := []uint{ps.goal}
+	for n := ps.goal; n != start; n = ps.parent[n] {
+		chain = append(chain, ps.parent[n])
+	}
+	path := make([]MatrixPos, 0, len(chain))
+	for i := len(chain) - 1; i >= 0; i-- {
+		r, c := ps.m.asRC(chain[i])
+		if len(path) > 0 {
+			prev := path[len(path)-1]
+			dr, dc := unitStep(int(r)-int(prev.r)), unitStep(int(c)-int(prev.c))
+			pr, pc := int(prev.r)+dr, int(prev.c)+dc
+			for ; pr != int(r) || pc != int(c); pr, pc = pr+dr, pc+dc {
+				path = append(path, NewMatrixPos(uint(pr), uint(pc), ps.m.C))
+			}
+		}
+		path = append(path, NewMatrixPos(r, c, ps.m.C))
+	}
+	return path
+}
+
+func unitStep(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// Jump point search successors, the jump points found heading off in
+// each direction left after pruning by the direction n was reached from
+func (ps *pathSearch) jumpSuccessors(n uint, fn func(next uint, step float64)) {
+	r, c := ps.m.asRC(n)
+	for _, d := range ps.jumpDirections(n) {
+		jr, jc, ok := ps.jump(int(r)+d[0], int(c)+d[1], d[0], d[1])
+		if !ok {
+			continue
+		}
+		dr, dc := math.Abs(float64(jr)-float64(r)), math.Abs(float64(jc)-float64(c))
+		fn(ps.m.index(uint(jr), uint(jc)), octile(dr, dc))
+	}
+}
+
+// Natural and forced neighbor directions for the no corner cutting rules
+func (ps *pathSearch) jumpDirections(n uint) [][2]int {
+	dirs := make([][2]int, 0, 8)
+	if ps.costOf(n) == 0 {
+		ps.neighbors(n, func(next uint, _ float64) {
+			nr, nc := ps.m.asRC(next)
+			r, c := ps.m.asRC(n)
+			dirs = append(dirs, [2]int{int(nr) - int(r), int(nc) - int(c)})
+		})
+		return dirs
+	}
+	r, c := ps.m.asRC(n)
+	pr, pc := ps.m.asRC(ps.parent[n])
+	dr, dc := unitStep(int(r)-int(pr)), unitStep(int(c)-int(pc))
+	ri, ci := int(r), int(c)
+	switch {
+	case dr != 0 && dc != 0:
+		alongR, alongC := ps.walkable(ri+dr, ci), ps.walkable(ri, ci+dc)
+		if alongR {
+			dirs = append(dirs, [2]int{dr, 0})
+		}
+		if alongC {
+			dirs = append(dirs, [2]int{0, dc})
+		}
+		if alongR && alongC {
+			dirs = append(dirs, [2]int{dr, dc})
+		}
+	case dr == 0:
+		below, above := ps.walkable(ri+1, ci), ps.walkable(ri-1, ci)
+		if ps.walkable(ri, ci+dc) {
+			dirs = append(dirs, [2]int{0, dc})
+			if below {
+				dirs = append(dirs, [2]int{1, dc})
+			}
+			if above {
+				dirs = append(dirs, [2]int{-1, dc})
+			}
+		}
+		if below {
+			dirs = append(dirs, [2]int{1, 0})
+		}
+		if above {
+			dirs = append(dirs, [2]int{-1, 0})
+		}
+	default:
+		right, left := ps.walkable(ri, ci+1), ps.walkable(ri, ci-1)
+		if ps.walkable(ri+dr, ci) {
+			dirs = append(dirs, [2]int{dr, 0})
+			if right {
+				dirs = append(dirs, [2]int{dr, 1})
+			}
+			if left {
+				dirs = append(dirs, [2]int{dr, -1})
+			}
+		}
+		if right {
+			dirs = append(dirs, [2]int{0, 1})
+		}
+		if left {
+			dirs = append(dirs, [2]int{0, -1})
+		}
+	}
+	return dirs
+}
+
+// The next jump point from r, c heading dr, dc, false when the run
+// hits a blocked cell first
+func (ps *pathSearch) jump(r, c, dr, dc int) (int, int, bool) {
+	for {
+		if !ps.walkable(r, c) {
+			return 0, 0, false
+		}
+		if ps.m.index(uint(r), uint(c)) == ps.goal {
+			return r, c, true
+		}
+		if dr != 0 && dc != 0 {
+			if _, _, ok := ps.jump(r+dr, c, dr, 0); ok {
+				return r, c, true
+			}
+			if _, _, ok := ps.jump(r, c+dc, 0, dc); ok {
+				return r, c, true
+			}
+		} else if ps.forced(r, c, dr, dc) {
+			return r, c, true
+		}
+		if !ps.walkable(r+dr, c) || !ps.walkable(r, c+dc) {
+			return 0, 0, false
+		}
+		r, c = r+dr, c+dc
+	}
+}
+
+// A straight run has a forced neighbor where a wall beside it ends
+func (ps *pathSearch) forced(r, c, dr, dc int) bool {
+	if dr == 0 {
+		return ps.walkable(r-1, c) && !ps.walkable(r-1, c-dc) ||
+			ps.walkable(r+1, c) && !ps.walkable(r+1, c-dc)
+	}
+	return ps.walkable(r, c-1) && !ps.walkable(r-dr, c-1) ||
+		ps.walkable(r, c+1) && !ps.walkable(r-dr, c+1)
+}
+
+type pathItem struct {
+	n    uint
+	g, f float64
+}
+
+// Min heap on f, the deeper item wins ties
+type pathQueue []pathItem
+
+func (pq pathQueue) Len() int {
+	return len(pq)
+}
+
+func (pq pathQueue) Less(x, y int) bool {
+	if pq[x].f == pq[y].f {
+		return pq[x].g > pq[y].g
+	}
+	return pq[x].f < pq[y].f
+}
+
+func (pq pathQueue) Swap(x, y int) {
+	pq[x], pq[y] = pq[y], pq[x]
+}
+
+func (pq *pathQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(pathItem))
+}
+
+func (pq *pathQueue) Pop() interface{} {
+	old := *pq
+	item := old[len(old)-1]
+	*pq = old[:len(old)-1]
+	return item
+}
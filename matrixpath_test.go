@@ -0,0 +1,228 @@
+package matrixbitset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Every step moves one cell onto a walkable one, diagonals obeying corners
+func checkPath(t *testing.T, m *MatrixBitSet, path []MatrixPos, eight bool, corners CornerRule) float64 {
+	t.Helper()
+	cost := 0.0
+	for i, mp := range path {
+		if !m.Test(mp.r, mp.c) {
+			t.Fatalf("Path steps onto blocked %v", mp)
+		}
+		if i == 0 {
+			continue
+		}
+		dr, dc := int(mp.r)-int(path[i-1].r), int(mp.c)-int(path[i-1].c)
+		if abs(dr) > 1 || abs(dc) > 1 || dr == 0 && dc == 0 {
+			t.Fatalf("Path jumps from %v to %v", path[i-1], mp)
+		}
+		if dr == 0 || dc == 0 {
+			cost++
+			continue
+		}
+		if !eight {
+			t.Fatalf("Diagonal step from %v to %v", path[i-1], mp)
+		}
+		open := 0
+		if m.Test(path[i-1].r, mp.c) {
+			open++
+		}
+		if m.Test(mp.r, path[i-1].c) {
+			open++
+		}
+		if corners == CornerCutNever && open < 2 || corners == CornerCutOne && open < 1 {
+			t.Fatalf("Diagonal step from %v to %v cuts a corner", path[i-1], mp)
+		}
+		cost += math.Sqrt2
+	}
+	return cost
+}
+
+// Cheapest cost to every cell by relaxing until nothing changes
+func bruteCosts(m *MatrixBitSet, from MatrixPos, eight bool, corners CornerRule) []float64 {
+	costs := make([]float64, m.R*m.C)
+	for i := range costs {
+		costs[i] = math.Inf(1)
+	}
+	costs[m.index(from.r, from.c)] = 0
+	walkable := func(r, c int) bool {
+		return r >= 0 && c >= 0 && r < int(m.R) && c < int(m.C) && m.Test(uint(r), uint(c))
+	}
+	for changed := true; changed; {
+		changed = false
+		for n := range costs {
+			r, c := int(n)/int(m.C), int(n)%int(m.C)
+			if !walkable(r, c) {
+				continue
+			}
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					if dr == 0 && dc == 0 || !walkable(r+dr, c+dc) {
+						continue
+					}
+					step := 1.0
+					if dr != 0 && dc != 0 {
+						if !eight {
+							continue
+						}
+						open := 0
+						if walkable(r+dr, c) {
+							open++
+						}
+						if walkable(r, c+dc) {
+							open++
+						}
+						if corners == CornerCutNever && open < 2 || corners == CornerCutOne && open < 1 {
+							continue
+						}
+						step = math.Sqrt2
+					}
+					next := (r+dr)*int(m.C) + c + dc
+					if costs[n]+step < costs[next]-1e-9 {
+						costs[next] = costs[n] + step
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return costs
+}
+
+func TestFindPathMaze(t *testing.T) {
+	m := shapeFromRows([]string{
+		"#.#####",
+		"#.#...#",
+		"#.#.#.#",
+		"###.#.#",
+		"....#.#",
+	})
+	from, to := NewMatrixPos(0, 0, m.C), NewMatrixPos(4, 6, m.C)
+	for _, algorithm := range []PathAlgorithm{BreadthFirst, Dijkstra, AStar} {
+		path, reached, ok := m.FindPath(from, to, WithAlgorithm(algorithm))
+		if !ok {
+			t.Fatalf("Algorithm %d found no path", algorithm)
+		}
+		if len(path) != 17 || path[0] != from || path[len(path)-1] != to {
+			t.Errorf("Algorithm %d: unexpected path %v", algorithm, path)
+		}
+		checkPath(t, m, path, false, CornerCutNever)
+		if !reached.TestN(from.N()) || !reached.TestN(to.N()) {
+			t.Errorf("Algorithm %d: expected both ends reached", algorithm)
+		}
+	}
+}
+
+func TestFindPathAgainstBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(11))
+	algorithms := []PathAlgorithm{Dijkstra, AStar, JumpPoint}
+	for trial := 0; trial < 80; trial++ {
+		m := randomMatrix(rnd, uint(3+rnd.Intn(14)), uint(3+rnd.Intn(14)), 0.7)
+		from := m.NewPos(uint(rnd.Intn(int(m.R * m.C))))
+		to := m.NewPos(uint(rnd.Intn(int(m.R * m.C))))
+		m.Set(from.r, from.c).Set(to.r, to.c)
+		for _, eight := range []bool{false, true} {
+			connectivity := FourConnected
+			if eight {
+				connectivity = EightConnected
+			}
+			for _, corners := range []CornerRule{CornerCutNever, CornerCutOne, CornerCutAlways} {
+				costs := bruteCosts(m, from, eight, corners)
+				expected := costs[to.N()]
+				for _, algorithm := range algorithms {
+					path, _, ok := m.FindPath(from, to, WithAlgorithm(algorithm),
+						WithConnectivity(connectivity), WithCornerRule(corners))
+					if ok != !math.IsInf(expected, 1) {
+						t.Fatalf("Algorithm %d: expected found %v, received %v", algorithm, !ok, ok)
+					}
+					if !ok {
+						continue
+					}
+					if cost := checkPath(t, m, path, eight, corners); math.Abs(cost-expected) > 1e-9 {
+						t.Errorf("Algorithm %d, eight %v, corners %d: expected cost %.3f, received %.3f",
+							algorithm, eight, corners, expected, cost)
+					}
+				}
+
+				// BFS counts steps, which only match costs without diagonals
+				if !eight {
+					if path, _, ok := m.FindPath(from, to, WithAlgorithm(BreadthFirst)); ok && float64(len(path)-1) != expected {
+						t.Errorf("Expected %v steps, received %d", expected, len(path)-1)
+					}
+				}
+
+				reachable := m.Reachable(from, WithConnectivity(connectivity), WithCornerRule(corners))
+				for n, cost := range costs {
+					if reachable.TestN(uint(n)) == math.IsInf(cost, 1) {
+						t.Fatalf("Reachable disagrees at %d", n)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFindPathCornerRules(t *testing.T) {
+	m := shapeFromRows([]string{
+		"#.",
+		".#",
+	})
+	from, to := NewMatrixPos(0, 0, m.C), NewMatrixPos(1, 1, m.C)
+	for _, tc := range []struct {
+		corners CornerRule
+		found   bool
+	}{{CornerCutNever, false}, {CornerCutOne, false}, {CornerCutAlways, true}} {
+		if _, _, ok := m.FindPath(from, to, WithConnectivity(EightConnected), WithCornerRule(tc.corners)); ok != tc.found {
+			t.Errorf("Corner rule %d: expected found %v", tc.corners, tc.found)
+		}
+	}
+
+	m.Set(0, 1)
+	path, _, _ := m.FindPath(from, to, WithConnectivity(EightConnected), WithCornerRule(CornerCutOne))
+	if len(path) != 2 {
+		t.Errorf("Expected one diagonal step, received %v", path)
+	}
+	path, _, _ = m.FindPath(from, to, WithConnectivity(EightConnected))
+	if len(path) != 3 {
+		t.Errorf("Expected to go around the corner, received %v", path)
+	}
+}
+
+func TestFindPathUnreachable(t *testing.T) {
+	m := shapeFromRows([]string{
+		"##.##",
+		"##.##",
+	})
+	from, to := NewMatrixPos(0, 0, m.C), NewMatrixPos(1, 4, m.C)
+	if _, reached, ok := m.FindPath(from, to); ok || reached.Count() != 4 {
+		t.Errorf("Expected no path with the left 4 cells reached, received %v, %d", ok, reached.Count())
+	}
+	if _, _, ok := m.FindPath(from, NewMatrixPos(0, 2, m.C)); ok {
+		t.Error("Expected no path onto a blocked cell")
+	}
+
+	// the clear cells are a path of their own
+	path, _, ok := m.FindPath(NewMatrixPos(0, 2, m.C), NewMatrixPos(1, 2, m.C), WithWalkable(false))
+	if !ok || len(path) != 2 {
+		t.Errorf("Expected a 2 cell path over the clear cells, received %v", path)
+	}
+}
+
+func TestFindPathStaysLocal(t *testing.T) {
+	m := NewMatrixBitSet(2048, 2048)
+	m.Fill(1000, 1000, 10, 10)
+	ps := m.newPathSearch([]PathOption{WithAlgorithm(AStar)})
+	start := m.index(1000, 1000)
+	ps.goal = m.index(1009, 1009)
+	if !ps.bestFirst(start, ps.estimate, ps.neighbors) {
+		t.Fatal("Expected a path across the square")
+	}
+	if len(ps.cost) > 100 || len(ps.parent) > 100 {
+		t.Errorf("Expected only the square's cells tracked, received %d costs and %d parents", len(ps.cost), len(ps.parent))
+	}
+}
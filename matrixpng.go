@@ -0,0 +1,48 @@
+package matrixbitset
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// An image.PalettedImage reading straight from the bits, nothing is copied
+// Clear pixels are Palette[0] and set pixels Palette[1], so image/png
+// writes it 1 bit per pixel
+type BitImage struct {
+	M       *MatrixBitSet
+	Palette color.Palette
+}
+
+func (m *MatrixBitSet) AsBitImage(clr, background color.Color) *BitImage {
+	return &BitImage{M: m, Palette: color.Palette{background, clr}}
+}
+
+func (bi *BitImage) ColorModel() color.Model {
+	return bi.Palette
+}
+
+func (bi *BitImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, int(bi.M.C), int(bi.M.R))
+}
+
+// Like image.Paletted, the background outside the bounds
+func (bi *BitImage) At(x, y int) color.Color {
+	return bi.Palette[bi.ColorIndexAt(x, y)]
+}
+
+func (bi *BitImage) ColorIndexAt(x, y int) uint8 {
+	if !(image.Point{X: x, Y: y}).In(bi.Bounds()) {
+		return 0
+	}
+	if bi.M.test(bi.M.index(uint(y), uint(x))) {
+		return 1
+	}
+	return 0
+}
+
+// A 1 bit paletted PNG, a transparent background is kept as such
+func (m *MatrixBitSet) WritePNG(w io.Writer, clr, background color.Color) error {
+	return png.Encode(w, m.AsBitImage(clr, background))
+}
@@ -0,0 +1,55 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestBitImage(t *testing.T) {
+	m, _ := ParseASCII("#..\n.#.")
+	white, black := color.Gray{Y: 255}, color.Gray{}
+	img := m.AsBitImage(black, white)
+	if img.Bounds() != image.Rect(0, 0, 3, 2) {
+		t.Errorf("Unexpected bounds %v", img.Bounds())
+	}
+	if img.At(0, 0) != black || img.At(1, 0) != white || img.At(1, 1) != black {
+		t.Error("Expected set pixels black and clear ones white")
+	}
+	if img.At(-1, 0) != white || img.ColorIndexAt(3, 0) != 0 {
+		t.Error("Expected the background outside the bounds")
+	}
+	var _ image.PalettedImage = img
+}
+
+func TestWritePNG(t *testing.T) {
+	m := randomMatrix(rand.New(rand.NewSource(37)), 61, 13, 0.4)
+	clr, background := color.NRGBA{R: 255, A: 255}, color.NRGBA{}
+	var b bytes.Buffer
+	if err := m.WritePNG(&b, clr, background); err != nil {
+		t.Fatal(err)
+	}
+	// IHDR: 8 byte signature, length, type, width, height, then bit depth and color type
+	header := b.Bytes()
+	if header[24] != 1 || header[25] != 3 {
+		t.Errorf("Expected a 1 bit paletted PNG, received depth %d type %d", header[24], header[25])
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < int(m.R); y++ {
+		for x := 0; x < int(m.C); x++ {
+			expected := color.NRGBAModel.Convert(background)
+			if m.Test(uint(y), uint(x)) {
+				expected = clr
+			}
+			if received := color.NRGBAModel.Convert(img.At(x, y)); received != expected {
+				t.Fatalf("[%d, %d]: expected %v, received %v", y, x, expected, received)
+			}
+		}
+	}
+}
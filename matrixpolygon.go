@@ -0,0 +1,170 @@
+package matrixbitset
+
+import (
+	"math"
+)
+
+// Twice the signed area, exact for grid coordinates
+// > 0 is counter clockwise as displayed (origin top left) matching Orient
+func (lr LinearRing) Area2() int {
+	area := 0
+	for i := 0; i+1 < len(lr); i++ {
+		area += lr[i].Row_i()*lr[i+1].Col_i() - lr[i+1].Row_i()*lr[i].Col_i()
+	}
+	return area
+}
+
+// Twice the area of the outer ring less its holes
+func (p *Polygon) Area2() int {
+	area := abs(p.Outer.Area2())
+	for _, hole := range p.Holes {
+		area -= abs(hole.Area2())
+	}
+	return area
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Which border follower ExtractAllPolygons uses
+type Tracer int
+
+const (
+	// TraceShell, 4 connected, drops rings under 5 points
+	ShellTracer Tracer = iota
+	// Suzuki-Abe border following, 8 connected shapes, 4 connected holes
+	SuzukiAbeTracer
+)
+
+type PolygonOption func(*polygonOptions)
+
+type polygonOptions struct {
+	tracer Tracer
+}
+
+func WithTracer(tracer Tracer) PolygonOption {
+	return func(po *polygonOptions) {
+		po.tracer = tracer
+	}
+}
+
+func newPolygonOptions(opts []PolygonOption) *polygonOptions {
+	po := &polygonOptions{tracer: ShellTracer}
+	for _, opt := range opts {
+		opt(po)
+	}
+	return po
+}
+
+// Reverses in place, flipping the orientation
+// Borrowed from SliceTricks https://github.com/golang/go/wiki/SliceTricks
+func (lr LinearRing) reverse() {
+	for left, right := 0, len(lr)-1; left < right; left, right = left+1, right-1 {
+		lr[left], lr[right] = lr[right], lr[left]
+	}
+}
+
+// Is the point strictly inside the ring? Points on the ring itself
+// may go either way. Exact for grid coordinates, even odd rule
+func (lr LinearRing) containsPoint(r, c int) bool {
+	inside := false
+	for i, j := 0, len(lr)-1; i < len(lr); j, i = i, i+1 {
+		ri, ci := lr[i].Row_i(), lr[i].Col_i()
+		rj, cj := lr[j].Row_i(), lr[j].Col_i()
+		if (ri > r) != (rj > r) {
+			// c < the crossing col, with the division multiplied out
+			lhs, rhs := (c-ci)*(rj-ri), (cj-ci)*(r-ri)
+			if (rj > ri && lhs < rhs) || (rj < ri && lhs > rhs) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Where a point sits relative to a ring or polygon
+type Location int
+
+const (
+	Outside Location = iota
+	OnBoundary
+	Inside
+)
+
+// Winding number of the ring around [r, c], 0 means outside
+// non zero means inside, with the sign following the orientation
+// Adapted from Dan Sunday's http://geomalgorithms.com/a03-_inclusion.html
+func (lr LinearRing) WindingNumber(r, c float64) int {
+	return windingNumber(len(lr), func(i int) (float64, float64) {
+		return float64(lr[i].r), float64(lr[i].c)
+	}, r, c)
+}
+
+// Inside, OnBoundary or Outside the ring, by winding number
+// Exact whenever r, c are grid points or half way between them
+func (lr LinearRing) Locate(r, c float64) Location {
+	return locate(len(lr), func(i int) (float64, float64) {
+		return float64(lr[i].r), float64(lr[i].c)
+	}, r, c)
+}
+
+// Locate, with holes taken out of the outer ring
+// a point on a hole's ring is OnBoundary
+func (p *Polygon) Locate(r, c float64) Location {
+	where := p.Outer.Locate(r, c)
+	if where != Inside {
+		return where
+	}
+	for _, hole := range p.Holes {
+		switch hole.Locate(r, c) {
+		case OnBoundary:
+			return OnBoundary
+		case Inside:
+			return Outside
+		}
+	}
+	return Inside
+}
+
+// Inside or OnBoundary
+func (p *Polygon) Contains(r, c float64) bool {
+	return p.Locate(r, c) != Outside
+}
+
+// The ring's vertexes come from at, which may or may not repeat the first
+func locate(n int, at func(i int) (float64, float64), r, c float64) Location {
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		ri, ci := at(i)
+		rj, cj := at(j)
+		if (rj-ri)*(c-ci)-(cj-ci)*(r-ri) == 0 &&
+			math.Min(ri, rj) <= r && r <= math.Max(ri, rj) && math.Min(ci, cj) <= c && c <= math.Max(ci, cj) {
+			return OnBoundary
+		}
+	}
+	if windingNumber(n, at, r, c) != 0 {
+		return Inside
+	}
+	return Outside
+}
+
+func windingNumber(n int, at func(i int) (float64, float64), r, c float64) int {
+	wn := 0
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		rj, cj := at(j)
+		ri, ci := at(i)
+		// > 0 when [r, c] is left of the edge j -> i, taking cols as x, rows as y
+		left := (ci-cj)*(r-rj) - (c-cj)*(ri-rj)
+		if rj <= r {
+			if ri > r && left > 0 {
+				wn++
+			}
+		} else if ri <= r && left < 0 {
+			wn--
+		}
+	}
+	return wn
+}
@@ -0,0 +1,59 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	// a U opening upwards, counter clockwise
+	u := ringOf([2]uint{0, 0}, [2]uint{10, 0}, [2]uint{10, 10}, [2]uint{0, 10}, [2]uint{0, 7}, [2]uint{6, 7}, [2]uint{6, 3}, [2]uint{0, 3}, [2]uint{0, 0})
+	for _, expected := range []struct {
+		r, c  float64
+		where Location
+	}{
+		{8, 5, Inside},
+		{3, 5, Outside}, // in the notch
+		{0.5, 1.5, Inside},
+		{6, 5, OnBoundary},
+		{2.5, 7, OnBoundary},
+		{11, 5, Outside},
+		{-1, 5, Outside},
+	} {
+		if where := u.Locate(expected.r, expected.c); where != expected.where {
+			t.Errorf("Expected [%v, %v] %d, received %d", expected.r, expected.c, expected.where, where)
+		}
+	}
+	if wn := u.WindingNumber(8, 5); wn == 0 {
+		t.Error("Expected a non zero winding number inside")
+	}
+	reversed := append(LinearRing(nil), u...)
+	reversed.reverse()
+	if u.WindingNumber(8, 5) != -reversed.WindingNumber(8, 5) {
+		t.Error("Expected reversing the ring to flip the winding number")
+	}
+
+	p := squarePolygon(0, 0, 30)
+	p.Holes = []LinearRing{squarePolygon(10, 10, 10).Outer}
+	if p.Locate(15, 15) != Outside || p.Locate(10, 15) != OnBoundary || p.Locate(5.25, 15) != Inside {
+		t.Error("Expected the hole taken out of the polygon")
+	}
+	if !p.Contains(20, 20) || p.Contains(15.5, 15.5) {
+		t.Error("Expected Contains to include the boundary, but not the hole")
+	}
+}
+
+func TestNInsideDecreasingCoords(t *testing.T) {
+	m := NewMatrixBitSet(20, 20)
+	// the right edge runs back up, which used to underflow
+	ring := ringOf([2]uint{2, 2}, [2]uint{15, 2}, [2]uint{15, 15}, [2]uint{8, 15}, [2]uint{8, 9}, [2]uint{2, 9}, [2]uint{2, 2})
+	bounds := NewMatrixBounds(m, ring)
+	if !bounds.NInside(m.index(10, 10)) {
+		t.Error("Expected [10, 10] inside")
+	}
+	if bounds.NInside(m.index(4, 12)) {
+		t.Error("Expected [4, 12] outside, it's in the cut out corner")
+	}
+	if !bounds.NInside(m.index(15, 5)) || bounds.NStrictlyInside(m.index(15, 5)) {
+		t.Error("Expected [15, 5] on the edge, inside but not strictly")
+	}
+}
@@ -0,0 +1,149 @@
+package matrixbitset
+
+// The nesting ExtractAllPolygons flattens away. Roots are outer rings
+// at Depth 0, their children are holes at Depth 1, islands sitting in
+// those holes are Depth 2 and so on, outer rings always at even depths
+type PolygonTree struct {
+	Roots []*PolygonNode
+}
+
+type PolygonNode struct {
+	Ring     LinearRing
+	Hole     bool
+	Depth    int
+	Parent   *PolygonNode
+	Children []*PolygonNode
+}
+
+// Same rings as ExtractAllPolygons with the same options, but nested
+func (m *MatrixBitSet) ExtractPolygonTree(opts ...PolygonOption) (*PolygonTree, bool) {
+	if newPolygonOptions(opts).tracer == SuzukiAbeTracer {
+		return m.suzukiAbeTree(), true
+	}
+	polygons, ok := m.ExtractAllPolygons(opts...)
+	if !ok {
+		return nil, false
+	}
+	return nestPolygons(polygons), true
+}
+
+// Suzuki-Abe already knows every border's parent
+func (m *MatrixBitSet) suzukiAbeTree() *PolygonTree {
+	borders := m.suzukiAbe()
+	nodes := make([]*PolygonNode, len(borders))
+	tree := &PolygonTree{Roots: make([]*PolygonNode, 0)}
+	// parents are always found before their children
+	for i, b := range borders {
+		node := &PolygonNode{Ring: b.ring, Hole: !b.outer, Children: make([]*PolygonNode, 0)}
+		nodes[i] = node
+		if b.parent < 0 {
+			tree.Roots = append(tree.Roots, node)
+		} else {
+			nodes[b.parent].adopt(node)
+		}
+	}
+	return tree
+}
+
+// Hangs each polygon under the smallest hole of another polygon around it
+func nestPolygons(polygons []*Polygon) *PolygonTree {
+	outers := make([]*PolygonNode, len(polygons))
+	holes := make([]*PolygonNode, 0)
+	for i, p := range polygons {
+		outers[i] = &PolygonNode{Ring: p.Outer, Children: make([]*PolygonNode, 0)}
+		for _, h := range p.Holes {
+			hole := &PolygonNode{Ring: h, Hole: true, Children: make([]*PolygonNode, 0)}
+			outers[i].Children = append(outers[i].Children, hole)
+			hole.Parent = outers[i]
+			holes = append(holes, hole)
+		}
+	}
+
+	tree := &PolygonTree{Roots: make([]*PolygonNode, 0)}
+	for _, outer := range outers {
+		r, c := outer.Ring[0].Both_i()
+		var owner *PolygonNode
+		for _, hole := range holes {
+			if hole.Parent != outer && hole.Ring.containsPoint(r, c) &&
+				(owner == nil || abs(hole.Ring.Area2()) < abs(owner.Ring.Area2())) {
+				owner = hole
+			}
+		}
+		if owner == nil {
+			tree.Roots = append(tree.Roots, outer)
+		} else {
+			owner.Children = append(owner.Children, outer)
+			outer.Parent = owner
+		}
+	}
+	for _, root := range tree.Roots {
+		root.setDepth(0)
+	}
+	return tree
+}
+
+func (pn *PolygonNode) adopt(child *PolygonNode) {
+	child.Parent = pn
+	child.Depth = pn.Depth + 1
+	pn.Children = append(pn.Children, child)
+}
+
+func (pn *PolygonNode) setDepth(depth int) {
+	pn.Depth = depth
+	for _, child := range pn.Children {
+		child.setDepth(depth + 1)
+	}
+}
+
+// The outer ring and its holes, only meaningful for non hole nodes
+func (pn *PolygonNode) Polygon() *Polygon {
+	polygon := &Polygon{Outer: pn.Ring, Holes: make([]LinearRing, 0, len(pn.Children))}
+	for _, child := range pn.Children {
+		if child.Hole {
+			polygon.Holes = append(polygon.Holes, child.Ring)
+		}
+	}
+	return polygon
+}
+
+// Depth first, parents before children, return false to skip a node's children
+func (pt *PolygonTree) Walk(fn func(node *PolygonNode) bool) {
+	var walk func(nodes []*PolygonNode)
+	walk = func(nodes []*PolygonNode) {
+		for _, node := range nodes {
+			if fn(node) {
+				walk(node.Children)
+			}
+		}
+	}
+	walk(pt.Roots)
+}
+
+// Every outer ring with its holes, like ExtractAllPolygons returns
+func (pt *PolygonTree) Polygons() []*Polygon {
+	polygons := make([]*Polygon, 0, 128)
+	pt.Walk(func(node *PolygonNode) bool {
+		if !node.Hole {
+			polygons = append(polygons, node.Polygon())
+		}
+		return true
+	})
+	return polygons
+}
+
+// Polygons grouped by even odd level, level n holds
+// the outer rings at Depth 2n with their holes
+func (pt *PolygonTree) Levels() [][]*Polygon {
+	levels := make([][]*Polygon, 0)
+	pt.Walk(func(node *PolygonNode) bool {
+		if !node.Hole {
+			level := node.Depth / 2
+			for len(levels) <= level {
+				levels = append(levels, make([]*Polygon, 0))
+			}
+			levels[level] = append(levels[level], node.Polygon())
+		}
+		return true
+	})
+	return levels
+}
@@ -0,0 +1,91 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func TestPolygonTree(t *testing.T) {
+	// a ring, an island in its hole, an island in the island's hole
+	// and an unrelated square
+	m, _ := ParseASCII(`
+		..............................
+		.########################.###.
+		.########################.###.
+		.########################.###.
+		.###..................###.....
+		.###..................###.....
+		.###..##############..###.....
+		.###..##############..###.....
+		.###..##############..###.....
+		.###..###........###..###.....
+		.###..###........###..###.....
+		.###..###..####..###..###.....
+		.###..###..####..###..###.....
+		.###..###..####..###..###.....
+		.###..###..####..###..###.....
+		.###..###........###..###.....
+		.###..###........###..###.....
+		.###..##############..###.....
+		.###..##############..###.....
+		.###..##############..###.....
+		.###..................###.....
+		.###..................###.....
+		.########################.....
+		.########################.....
+		.########################.....
+		..............................
+	`)
+	for _, tracer := range []Tracer{ShellTracer, SuzukiAbeTracer} {
+		tree, ok := m.ExtractPolygonTree(WithTracer(tracer))
+		if !ok {
+			t.Fatalf("tracer %d: ExtractPolygonTree failed", tracer)
+		}
+		if len(tree.Roots) != 2 {
+			t.Errorf("tracer %d: expected 2 roots, received %d", tracer, len(tree.Roots))
+		}
+		depths := make(map[int]int)
+		tree.Walk(func(node *PolygonNode) bool {
+			depths[node.Depth]++
+			if node.Hole != (node.Depth%2 == 1) {
+				t.Errorf("tracer %d: hole %v at depth %d", tracer, node.Hole, node.Depth)
+			}
+			if node.Parent != nil && node.Parent.Depth != node.Depth-1 {
+				t.Errorf("tracer %d: parent depth %d for depth %d", tracer, node.Parent.Depth, node.Depth)
+			}
+			return true
+		})
+		expected := map[int]int{0: 2, 1: 1, 2: 1, 3: 1, 4: 1}
+		for depth, count := range expected {
+			if depths[depth] != count {
+				t.Errorf("tracer %d: expected %d nodes at depth %d, received %d", tracer, count, depth, depths[depth])
+			}
+		}
+		levels := tree.Levels()
+		if len(levels) != 3 || len(levels[0]) != 2 || len(levels[1]) != 1 || len(levels[2]) != 1 {
+			t.Errorf("tracer %d: unexpected levels %v", tracer, levels)
+		}
+		if n := len(tree.Polygons()); n != 4 {
+			t.Errorf("tracer %d: expected 4 polygons, received %d", tracer, n)
+		}
+	}
+}
+
+func TestPolygonTreeWalkSkips(t *testing.T) {
+	m, _ := ParseASCII(`
+		.........
+		.###.....
+		.###.###.
+		.###.###.
+		.....###.
+		.........
+	`)
+	tree, _ := m.ExtractPolygonTree()
+	visited := 0
+	tree.Walk(func(node *PolygonNode) bool {
+		visited++
+		return false
+	})
+	if len(tree.Roots) != 2 || visited != len(tree.Roots) {
+		t.Errorf("Expected only the %d roots visited, received %d", len(tree.Roots), visited)
+	}
+}
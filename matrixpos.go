@@ -58,7 +58,7 @@ func (mp *MatrixPos) Up() MatrixPos {
 }
 
 func (mp *MatrixPos) Down(maxRows uint) MatrixPos {
-	if mp.r < maxRows-1 {
+	if mp.r+1 < maxRows {
 		return NewMatrixPos(mp.r+1, mp.c, mp.stride)
 	}
 	return InvalidPos
@@ -72,7 +72,7 @@ func (mp *MatrixPos) Left() MatrixPos {
 }
 
 func (mp *MatrixPos) Right(maxCols uint) MatrixPos {
-	if mp.c < maxCols-1 {
+	if mp.c+1 < maxCols {
 		return NewMatrixPos(mp.r, mp.c+1, mp.stride)
 	}
 	return InvalidPos
@@ -0,0 +1,199 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// A set of positions backed by a growable MatrixBitSet keyed by linear index
+// so membership is a bit test and Points() come back in ByRows order.
+// Every position shares the set's stride, which comes from the matrix
+// passed to NewPosSetFor or the first position added, adding one with
+// another stride or a col past its stride panics
+type PosSet struct {
+	mutex sync.RWMutex
+	m     *MatrixBitSet
+	count uint
+	// no bit below low is on, lets First() skip the cleared prefix
+	low uint
+}
+
+func NewPosSet(points []MatrixPos) *PosSet {
+	set := &PosSet{}
+	for _, pt := range points {
+		set.add(pt)
+	}
+	return set
+}
+
+// An empty set sized for every position in m
+func NewPosSetFor(m *MatrixBitSet) *PosSet {
+	return &PosSet{m: NewMatrixBitSet(m.C, m.R)}
+}
+
+// Invalid positions are never added
+func (ps *PosSet) Add(mp MatrixPos) bool {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	return ps.add(mp)
+}
+
+func (ps *PosSet) Remove(mp MatrixPos) bool {
+	ps.mutex.Lock()
+	i, found := ps.indexOf(mp)
+	if found {
+		ps.m.clear(i)
+		ps.count--
+	}
+	ps.mutex.Unlock()
+	return found
+}
+
+func (ps *PosSet) Contains(mp MatrixPos) bool {
+	ps.mutex.RLock()
+	_, found := ps.indexOf(mp)
+	ps.mutex.RUnlock()
+	return found
+}
+
+func (ps *PosSet) IsEmpty() bool {
+	return ps.Len() == 0
+}
+
+func (ps *PosSet) Len() uint {
+	ps.mutex.RLock()
+	count := ps.count
+	ps.mutex.RUnlock()
+	return count
+}
+
+// The lowest position in ByRows order
+func (ps *PosSet) First() (MatrixPos, bool) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if ps.count == 0 {
+		return InvalidPos, false
+	}
+	i, _ := ps.m.nextSet(ps.low)
+	ps.low = i
+	return ps.m.NewPos(i), true
+}
+
+// Positions in ByRows order
+func (ps *PosSet) Points() []MatrixPos {
+	ps.mutex.RLock()
+	points := make([]MatrixPos, 0, ps.count)
+	if ps.m != nil {
+		for i, e := ps.m.nextSet(ps.low); e; i, e = ps.m.nextSet(i + 1) {
+			points = append(points, ps.m.NewPos(i))
+		}
+	}
+	ps.mutex.RUnlock()
+	return points
+}
+
+// Positions in either set, returns a new PosSet
+func (ps *PosSet) Union(other *PosSet) *PosSet {
+	return ps.combine(other, func(a, b uint64) uint64 { return a | b })
+}
+
+// Positions in both sets, returns a new PosSet
+func (ps *PosSet) Intersect(other *PosSet) *PosSet {
+	return ps.combine(other, func(a, b uint64) uint64 { return a & b })
+}
+
+// Positions in this set but not the other, returns a new PosSet
+func (ps *PosSet) Difference(other *PosSet) *PosSet {
+	return ps.combine(other, func(a, b uint64) uint64 { return a &^ b })
+}
+
+// A word at a time, panics when the strides differ. A set nothing was
+// ever added to has no stride yet and goes with any other
+func (ps *PosSet) combine(other *PosSet, op func(a, b uint64) uint64) *PosSet {
+	// copy other first so the two locks are never held together
+	theirs := other.clone()
+	result := ps.clone()
+	if result.m == nil && theirs.m == nil {
+		return result
+	}
+	if theirs.m == nil {
+		theirs.m = NewMatrixBitSet(result.m.C, 0)
+	}
+	if result.m == nil {
+		result.m = NewMatrixBitSet(theirs.m.C, 0)
+	}
+	if result.m.C != theirs.m.C {
+		panic(fmt.Sprintf("position set stride %d doesn't match %d", theirs.m.C, result.m.C))
+	}
+	if theirs.m.R > result.m.R {
+		result.growTo(theirs.m.R - 1)
+	}
+	result.count, result.low = 0, 0
+	for x := range result.m.B {
+		var w uint64
+		if x < len(theirs.m.B) {
+			w = theirs.m.B[x]
+		}
+		result.m.B[x] = op(result.m.B[x], w)
+		result.count += uint(bits.OnesCount64(result.m.B[x]))
+	}
+	return result
+}
+
+func (ps *PosSet) clone() *PosSet {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	c := &PosSet{count: ps.count, low: ps.low}
+	if ps.m != nil {
+		c.m = &MatrixBitSet{B: append([]uint64(nil), ps.m.B...), R: ps.m.R, C: ps.m.C}
+	}
+	return c
+}
+
+func (ps *PosSet) add(mp MatrixPos) bool {
+	if !mp.Valid() {
+		return false
+	}
+	if mp.c >= mp.stride {
+		panic(fmt.Sprintf("position [%d, %d] is past its stride %d", mp.r, mp.c, mp.stride))
+	}
+	if ps.m == nil {
+		ps.m = NewMatrixBitSet(mp.stride, mp.r+1)
+	}
+	if mp.stride != ps.m.C {
+		panic(fmt.Sprintf("position stride %d doesn't match the set's %d", mp.stride, ps.m.C))
+	}
+	if mp.r >= ps.m.R {
+		ps.growTo(mp.r)
+	}
+	i := ps.m.index(mp.r, mp.c)
+	if ps.m.test(i) {
+		return false
+	}
+	ps.m.set(i)
+	ps.count++
+	if i < ps.low {
+		ps.low = i
+	}
+	return true
+}
+
+// Makes room for row r, doubling so a stream of Adds stays linear
+func (ps *PosSet) growTo(r uint) {
+	rows := ps.m.R * 2
+	if rows < r+1 {
+		rows = r + 1
+	}
+	words := wordsNeeded(rows * ps.m.C)
+	ps.m.B = append(ps.m.B, make([]uint64, words-len(ps.m.B))...)
+	ps.m.R = rows
+}
+
+func (ps *PosSet) indexOf(mp MatrixPos) (uint, bool) {
+	if ps.m == nil || !mp.Valid() || mp.stride != ps.m.C || mp.r >= ps.m.R || mp.c >= ps.m.C {
+		return 0, false
+	}
+	i := ps.m.index(mp.r, mp.c)
+	return i, ps.m.test(i)
+}
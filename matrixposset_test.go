@@ -0,0 +1,85 @@
+package matrixbitset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPosSetOrdered(t *testing.T) {
+	set := NewPosSet([]MatrixPos{NewMatrixPos(9, 1, 10), NewMatrixPos(0, 5, 10), NewMatrixPos(3, 3, 10)})
+	if !set.Add(NewMatrixPos(40, 0, 10)) {
+		t.Error("Expected Add past the current rows to grow the set")
+	}
+	if set.Add(NewMatrixPos(3, 3, 10)) {
+		t.Error("Expected a duplicate Add to return false")
+	}
+	if set.Add(InvalidPos) {
+		t.Error("Expected InvalidPos to be refused")
+	}
+	expected := []MatrixPos{NewMatrixPos(0, 5, 10), NewMatrixPos(3, 3, 10), NewMatrixPos(9, 1, 10), NewMatrixPos(40, 0, 10)}
+	if points := set.Points(); !reflect.DeepEqual(points, expected) {
+		t.Errorf("Expected %v, received %v", expected, points)
+	}
+	if !set.Remove(NewMatrixPos(0, 5, 10)) || set.Remove(NewMatrixPos(0, 5, 10)) {
+		t.Error("Expected exactly one Remove of [0, 5] to succeed")
+	}
+	if first, ok := set.First(); !ok || first != NewMatrixPos(3, 3, 10) {
+		t.Errorf("Expected first [3, 3], received %v", first)
+	}
+	if set.Len() != 3 || set.Contains(NewMatrixPos(0, 5, 10)) || !set.Contains(NewMatrixPos(40, 0, 10)) {
+		t.Errorf("Unexpected contents %v", set.Points())
+	}
+}
+
+func TestPosSetAlgebra(t *testing.T) {
+	m := NewMatrixBitSet(100, 100)
+	a, b := NewPosSetFor(m), NewPosSet(nil)
+	for i := uint(0); i < 100; i++ {
+		a.Add(NewMatrixPos(i, i, 100))
+		if i%2 == 0 {
+			b.Add(NewMatrixPos(i, i, 100))
+		}
+		b.Add(NewMatrixPos(i, 99-i, 100))
+	}
+	if n := a.Union(b).Len(); n != 200 {
+		t.Errorf("Expected union of 200, received %d", n)
+	}
+	if n := a.Intersect(b).Len(); n != 50 {
+		t.Errorf("Expected intersection of 50, received %d", n)
+	}
+	diff := a.Difference(b)
+	if diff.Len() != 50 || diff.Contains(NewMatrixPos(2, 2, 100)) || !diff.Contains(NewMatrixPos(3, 3, 100)) {
+		t.Errorf("Unexpected difference %v", diff.Points())
+	}
+	if n := a.Union(NewPosSet(nil)).Len(); n != 100 {
+		t.Errorf("Expected a union with an empty set to keep 100, received %d", n)
+	}
+}
+
+func TestPosSetStrides(t *testing.T) {
+	set := NewPosSet([]MatrixPos{NewMatrixPos(3, 3, 7), NewMatrixPos(1, 0, 7)})
+	expected := []MatrixPos{NewMatrixPos(1, 0, 7), NewMatrixPos(3, 3, 7)}
+	if points := set.Union(NewPosSet([]MatrixPos{NewMatrixPos(1, 0, 7)})).Points(); !reflect.DeepEqual(points, expected) {
+		t.Errorf("Expected %v, received %v", expected, points)
+	}
+	if set.Contains(NewMatrixPos(3, 3, 100)) {
+		t.Error("Expected [3, 3] of stride 100 to be another position than [3, 3] of stride 7")
+	}
+	for name, fn := range map[string]func(){
+		"col past stride": func() { set.Add(NewMatrixPos(0, 7, 7)) },
+		"other stride":    func() { set.Add(NewMatrixPos(0, 0, 8)) },
+		"mixed union":     func() { set.Union(NewPosSet([]MatrixPos{NewMatrixPos(0, 0, 8)})) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s to panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+	if set.Len() != 2 {
+		t.Errorf("Expected the refused positions to leave 2, received %v", set.Points())
+	}
+}
@@ -0,0 +1,134 @@
+package matrixbitset
+
+import (
+	"math/bits"
+)
+
+// Rows are packed back to back in B, so a row rarely starts on a word.
+// Row at a time algorithms copy them out into word aligned slices,
+// where a whole row can be ORed or XORed a word at a time
+
+// Words needed for one aligned row
+func (m *MatrixBitSet) rowWordCount() int {
+	return wordsNeeded(m.C)
+}
+
+// Row r copied into words, bit c of the row is bit c%64 of word c/64
+func (m *MatrixBitSet) rowWords(r uint, words []uint64) []uint64 {
+	n := m.rowWordCount()
+	if words == nil {
+		words = make([]uint64, n)
+	}
+	start := m.index(r, 0)
+	for w := 0; w < n; w++ {
+		bit := start + uint(w)*wordSize
+		x, off := int(bit>>log2WordSize), bit&(wordSize-1)
+		val := m.B[x] >> off
+		if off > 0 && x+1 < len(m.B) {
+			val |= m.B[x+1] << (wordSize - off)
+		}
+		words[w] = val & rowMask(m.C, w)
+	}
+	return words
+}
+
+// Overwrites row r with words, the inverse of rowWords
+func (m *MatrixBitSet) setRowWords(r uint, words []uint64) {
+	start := m.index(r, 0)
+	for w := 0; w < m.rowWordCount(); w++ {
+		mask := rowMask(m.C, w)
+		val := words[w] & mask
+		bit := start + uint(w)*wordSize
+		x, off := int(bit>>log2WordSize), bit&(wordSize-1)
+		m.B[x] = m.B[x]&^(mask<<off) | val<<off
+		if off > 0 && x+1 < len(m.B) {
+			m.B[x+1] = m.B[x+1]&^(mask>>(wordSize-off)) | val>>(wordSize-off)
+		}
+	}
+}
+
+// All the rows, aligned
+func (m *MatrixBitSet) toRows() [][]uint64 {
+	rows := make([][]uint64, m.R)
+	for r := range rows {
+		rows[r] = m.rowWords(uint(r), nil)
+	}
+	return rows
+}
+
+// A new w x len(rows) matrix from aligned rows
+func fromRows(rows [][]uint64, w uint) *MatrixBitSet {
+	m := NewMatrixBitSet(w, uint(len(rows)))
+	for r, words := range rows {
+		m.setRowWords(uint(r), words)
+	}
+	return m
+}
+
+// The bits of word w that fall within a row of cols
+func rowMask(cols uint, w int) uint64 {
+	remaining := cols - uint(w)*wordSize
+	if remaining >= wordSize {
+		return allBits
+	}
+	return allBits >> (wordSize - remaining)
+}
+
+func orRow(dst, src []uint64) {
+	for w, v := range src {
+		dst[w] |= v
+	}
+}
+
+func xorRow(dst, src []uint64) {
+	for w, v := range src {
+		dst[w] ^= v
+	}
+}
+
+func testRow(row []uint64, c uint) bool {
+	return row[c>>log2WordSize]&(1<<(c&(wordSize-1))) != 0
+}
+
+// Rows padded to a byte, the first pixel in the high bit when msbFirst,
+// otherwise the low bit
+func (m *MatrixBitSet) packRows(msbFirst bool) []byte {
+	stride := (m.C + 7) / 8
+	packed := make([]byte, stride*m.R)
+	row := make([]uint64, m.rowWordCount())
+	for r := uint(0); r < m.R; r++ {
+		m.rowWords(r, row)
+		for i := uint(0); i < stride; i++ {
+			b := byte(row[i/8] >> (8 * (i % 8)))
+			if msbFirst {
+				b = bits.Reverse8(b)
+			}
+			packed[r*stride+i] = b
+		}
+	}
+	return packed
+}
+
+// Bytes rows of width packed by packRows take
+func packedSize(width, height uint) uint {
+	return (width + 7) / 8 * height
+}
+
+// Fills m from rows packed by packRows, at least packedSize bytes
+func (m *MatrixBitSet) setPackedRows(packed []byte, msbFirst bool) {
+	stride := (m.C + 7) / 8
+	row := make([]uint64, m.rowWordCount())
+	for r := uint(0); r < m.R; r++ {
+		for w := range row {
+			row[w] = 0
+		}
+		for i := uint(0); i < stride; i++ {
+			b := packed[r*stride+i]
+			if msbFirst {
+				b = bits.Reverse8(b)
+			}
+			row[i/8] |= uint64(b) << (8 * (i % 8))
+		}
+		m.setRowWords(r, row)
+	}
+}
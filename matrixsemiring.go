@@ -0,0 +1,147 @@
+package matrixbitset
+
+import (
+	"fmt"
+)
+
+// Matrix products over the boolean semiring (OR, AND), treating a
+// MatrixBitSet as a relation from its rows to its cols
+
+// Above this many inner rows Mul switches to the Method of Four Russians
+const fourRussiansThreshold = 256
+
+// Bits per Four Russians lookup table, 2^8 ORed row combinations each
+const fourRussiansBits = 8
+
+// The n x n identity
+func NewIdentity(n uint) *MatrixBitSet {
+	m := NewMatrixBitSet(n, n)
+	for i := uint(0); i < n; i++ {
+		m.set(m.index(i, i))
+	}
+	return m
+}
+
+// m · other, so [r, c] is on when any m[r, k] and other[k, c] both are
+// m's cols must match other's rows, returns a new M2
+func (m *MatrixBitSet) Mul(other *MatrixBitSet) (*MatrixBitSet, error) {
+	if m.C >= fourRussiansThreshold {
+		return m.MulFourRussians(other)
+	}
+	if m.C != other.R {
+		return nil, fmt.Errorf("can't multiply %d x %d by %d x %d", m.R, m.C, other.R, other.C)
+	}
+	rows := other.toRows()
+	product := make([][]uint64, m.R)
+	for r := uint(0); r < m.R; r++ {
+		product[r] = make([]uint64, other.rowWordCount())
+		end := m.index(r+1, 0)
+		// OR in other's row k for every k on in our row r
+		for i, e := m.nextSet(m.index(r, 0)); e && i < end; i, e = m.nextSet(i + 1) {
+			orRow(product[r], rows[i%m.C])
+		}
+	}
+	return fromRows(product, other.C), nil
+}
+
+// Same product as Mul, but other's rows are taken fourRussiansBits at a time
+// with every combination of them ORed up front, so each row of the product
+// costs one table lookup per group instead of one OR per bit
+func (m *MatrixBitSet) MulFourRussians(other *MatrixBitSet) (*MatrixBitSet, error) {
+	if m.C != other.R {
+		return nil, fmt.Errorf("can't multiply %d x %d by %d x %d", m.R, m.C, other.R, other.C)
+	}
+	rows := other.toRows()
+	ours := m.toRows()
+	words := other.rowWordCount()
+	product := make([][]uint64, m.R)
+	for r := range product {
+		product[r] = make([]uint64, words)
+	}
+
+	table := make([][]uint64, 1<<fourRussiansBits)
+	for i := range table {
+		table[i] = make([]uint64, words)
+	}
+	for k0 := uint(0); k0 < m.C; k0 += fourRussiansBits {
+		bits := minUint(fourRussiansBits, m.C-k0)
+		// each entry is the one without its highest bit, plus that row
+		for i := 1; i < 1<<bits; i++ {
+			high := uint(0)
+			for i>>(high+1) != 0 {
+				high++
+			}
+			copy(table[i], table[i&^(1<<high)])
+			orRow(table[i], rows[k0+high])
+		}
+		for r, row := range ours {
+			if key := groupBits(row, k0, bits); key != 0 {
+				orRow(product[r], table[key])
+			}
+		}
+	}
+	return fromRows(product, other.C), nil
+}
+
+// m multiplied by itself n times, Pow(0) is the identity
+func (m *MatrixBitSet) Pow(n uint) (*MatrixBitSet, error) {
+	if m.R != m.C {
+		return nil, fmt.Errorf("can't raise %d x %d to a power, it isn't square", m.R, m.C)
+	}
+	result := NewIdentity(m.R)
+	base := m
+	var err error
+	// square and multiply
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			if result, err = result.Mul(base); err != nil {
+				return nil, err
+			}
+		}
+		if n > 1 {
+			if base, err = base.Mul(base); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// Warshall's algorithm, [r, c] is on when c can be reached from r
+// in one or more steps, returns a new M2
+func (m *MatrixBitSet) TransitiveClosure() (*MatrixBitSet, error) {
+	if m.R != m.C {
+		return nil, fmt.Errorf("can't close %d x %d, it isn't square", m.R, m.C)
+	}
+	rows := m.toRows()
+	for k := uint(0); k < m.R; k++ {
+		for r := range rows {
+			if testRow(rows[r], k) {
+				orRow(rows[r], rows[k])
+			}
+		}
+	}
+	return fromRows(rows, m.C), nil
+}
+
+// TransitiveClosure with every [i, i] on as well, zero or more steps
+func (m *MatrixBitSet) ReflexiveTransitiveClosure() (*MatrixBitSet, error) {
+	closure, err := m.TransitiveClosure()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint(0); i < closure.R; i++ {
+		closure.set(closure.index(i, i))
+	}
+	return closure, nil
+}
+
+// The count bits of an aligned row starting at col k0 as an integer
+func groupBits(row []uint64, k0, count uint) uint {
+	x, off := k0>>log2WordSize, k0&(wordSize-1)
+	val := row[x] >> off
+	if off+count > wordSize && int(x)+1 < len(row) {
+		val |= row[x+1] << (wordSize - off)
+	}
+	return uint(val & (1<<count - 1))
+}
@@ -0,0 +1,107 @@
+package matrixbitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomMatrix(rnd *rand.Rand, w, h uint, density float64) *MatrixBitSet {
+	m := NewMatrixBitSet(w, h)
+	for i := uint(0); i < w*h; i++ {
+		if rnd.Float64() < density {
+			m.SetN(i)
+		}
+	}
+	return m
+}
+
+func bruteMul(a, b *MatrixBitSet) *MatrixBitSet {
+	product := NewMatrixBitSet(b.C, a.R)
+	for r := uint(0); r < a.R; r++ {
+		for c := uint(0); c < b.C; c++ {
+			for k := uint(0); k < a.C; k++ {
+				if a.Test(r, k) && b.Test(k, c) {
+					product.Set(r, c)
+					break
+				}
+			}
+		}
+	}
+	return product
+}
+
+func sameBits(a, b *MatrixBitSet) bool {
+	if a.R != b.R || a.C != b.C {
+		return false
+	}
+	for i := uint(0); i < a.R*a.C; i++ {
+		if a.test(i) != b.test(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRowWordsRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	m := randomMatrix(rnd, 131, 9, 0.5)
+	if copied := fromRows(m.toRows(), m.C); !sameBits(m, copied) {
+		t.Error("Expected rows to round trip")
+	}
+}
+
+func TestMul(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, dims := range [][3]uint{{3, 5, 7}, {64, 64, 64}, {70, 130, 65}, {20, 300, 90}} {
+		a := randomMatrix(rnd, dims[1], dims[0], 0.05)
+		b := randomMatrix(rnd, dims[2], dims[1], 0.05)
+		expected := bruteMul(a, b)
+		if product, err := a.Mul(b); err != nil || !sameBits(product, expected) {
+			t.Errorf("%v: Mul differs from brute force %v", dims, err)
+		}
+		if product, err := a.MulFourRussians(b); err != nil || !sameBits(product, expected) {
+			t.Errorf("%v: MulFourRussians differs from brute force %v", dims, err)
+		}
+	}
+	if _, err := NewMatrixBitSet(3, 2).Mul(NewMatrixBitSet(2, 2)); err == nil {
+		t.Error("Expected an error multiplying 2 x 3 by 2 x 2")
+	}
+}
+
+func TestPowAndClosure(t *testing.T) {
+	// a chain 0 -> 1 -> ... -> 9 and a loop 10 -> 11 -> 10
+	m := NewMatrixBitSet(12, 12)
+	for i := uint(0); i < 9; i++ {
+		m.Set(i, i+1)
+	}
+	m.Set(10, 11)
+	m.Set(11, 10)
+
+	if identity, _ := m.Pow(0); !sameBits(identity, NewIdentity(12)) {
+		t.Error("Expected Pow(0) to be the identity")
+	}
+	cubed, _ := m.Pow(3)
+	if !cubed.Test(2, 5) || cubed.Test(2, 4) || !cubed.Test(10, 11) || cubed.Test(10, 10) {
+		t.Error("Expected Pow(3) to be three steps")
+	}
+
+	closure, err := m.TransitiveClosure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r := uint(0); r < 12; r++ {
+		for c := uint(0); c < 12; c++ {
+			reachable := (r < 10 && c < 10 && c > r) || (r >= 10 && c >= 10)
+			if closure.Test(r, c) != reachable {
+				t.Errorf("Expected [%d, %d] reachable %v", r, c, reachable)
+			}
+		}
+	}
+	reflexive, _ := m.ReflexiveTransitiveClosure()
+	if !reflexive.Test(3, 3) || reflexive.Test(3, 2) {
+		t.Error("Expected the reflexive closure to add only the diagonal")
+	}
+	if _, err := NewMatrixBitSet(3, 2).TransitiveClosure(); err == nil {
+		t.Error("Expected an error closing a non square matrix")
+	}
+}
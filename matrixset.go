@@ -4,12 +4,9 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 	"math/bits"
-	"sort"
-
-	//	"sort"
 	"strings"
-	"sync"
 )
 
 // Shamelessly borrowed from https://github.com/willf/bitset
@@ -30,8 +27,41 @@ type MatrixBitSet struct {
 }
 
 func NewMatrixBitSet(w, h uint) *MatrixBitSet {
-	// Round up to the next uint64 if partial ie. not a multiple of 64
-	return &MatrixBitSet{B: make([]uint64, int((w*h)+(wordSize-1))>>log2WordSize), R: h, C: w}
+	return &MatrixBitSet{B: make([]uint64, wordsNeeded(w*h)), R: h, C: w}
+}
+
+// The most bits NewMatrixBitSetChecked, and so every decoder, will
+// allocate. 2^32 bits is 512 MiB, raise it for bigger matrices
+var MaxMatrixBits uint = 1 << 32
+
+// Same as NewMatrixBitSet, but refuses empty dimensions and
+// any w x h over MaxMatrixBits
+func NewMatrixBitSetChecked(w, h uint) (*MatrixBitSet, error) {
+	if err := checkDimensions(w, h); err != nil {
+		return nil, err
+	}
+	return NewMatrixBitSet(w, h), nil
+}
+
+// The error NewMatrixBitSetChecked gives for w x h, without allocating
+func checkDimensions(w, h uint) error {
+	if w == 0 || h == 0 {
+		return fmt.Errorf("matrix dimensions %d x %d must be non zero", h, w)
+	}
+	if w > math.MaxUint/h || w*h > MaxMatrixBits {
+		return fmt.Errorf("matrix dimensions %d x %d are over %d bits", h, w, MaxMatrixBits)
+	}
+	return nil
+}
+
+// Round up to the next uint64 if partial ie. not a multiple of 64
+// without letting n+63 wrap around
+func wordsNeeded(n uint) int {
+	words := n >> log2WordSize
+	if n&(wordSize-1) != 0 {
+		words++
+	}
+	return int(words)
 }
 
 func NewMatrixPos(r, c, stride uint) MatrixPos {
@@ -68,14 +98,29 @@ func (m *MatrixBitSet) ClearN(i uint) *MatrixBitSet {
 	return m.clear(i)
 }
 
+// Returns 0 for a matrix without rows rather than wrapping around,
+// the same as for a single row, so check IsEmpty to tell them apart
 func (m *MatrixBitSet) LastRow() uint {
+	if m.R == 0 {
+		return 0
+	}
 	return m.R - 1
 }
 
+// Returns 0 for a matrix without cols rather than wrapping around,
+// the same as for a single col, so check IsEmpty to tell them apart
 func (m *MatrixBitSet) LastCol() uint {
+	if m.C == 0 {
+		return 0
+	}
 	return m.C - 1
 }
 
+// True when the matrix has no rows or no cols
+func (m *MatrixBitSet) IsEmpty() bool {
+	return m.R == 0 || m.C == 0
+}
+
 // points are [r, c]
 func (m *MatrixBitSet) FillBox(ul, ur, lr, ll []uint) {
 	r, c := ul[0], ul[1]
@@ -120,8 +165,12 @@ func (b *MatrixBitSet) Count() uint {
 	return 0
 }
 
+// InvalidPos for a matrix without cols, which has no positions
 func (m *MatrixBitSet) NewPos(i uint) MatrixPos {
 	stride := m.C
+	if stride == 0 {
+		return InvalidPos
+	}
 	return MatrixPos{r: i / stride, c: i % stride, stride: stride}
 }
 
@@ -138,7 +187,7 @@ func (m *MatrixBitSet) ShrinkToBounds() (*MatrixBitSet, func(r, c uint) (uint, u
 
 // Creates the minimal *M2 that are contained by the passed bounds
 func (m *MatrixBitSet) Shrink(bounds *MatrixBounds) (*MatrixBitSet, func(r, c uint) (uint, uint), error) {
-	w, h := bounds.Width()+1, bounds.Height()+1
+	w, h := bounds.Width(), bounds.Height()
 
 	shrunk := NewMatrixBitSet(w, h)
 	for i, e := m.nextSet(0); e; i, e = m.nextSet(i + 1) {
@@ -163,26 +212,38 @@ func (m *MatrixBitSet) EraseBounds(bounds *MatrixBounds) {
 }
 
 // Is the passed index within the bounds (left, top, right, bottom)?
-// Adapted from https://wrf.ecse.rpi.edu//Research/Short_Notes/pnpoly.html
+// By winding number, pixels on the bounds' edges count
 func (mb *MatrixBounds) NInside(n uint) bool {
 	mb.M.panicOverSized(n)
-	nvert := len(mb.vertx)
-	testy, testx := n/mb.M.C, n%mb.M.C
+	return mb.locateN(n) != Outside
+}
 
-	inside := false
-	for i, j := 0, nvert-1; i < nvert; j, i = i, i+1 {
-		if (mb.verty[i] > testy) != (mb.verty[j] > testy) &&
-			testx < (mb.vertx[j]-mb.vertx[i])*(testy-mb.verty[i])/(mb.verty[j]-mb.verty[i])+mb.vertx[i] {
-			inside = !inside
-		}
-	}
-	return inside
+// Like NInside, but pixels on the bounds' edges don't count
+func (mb *MatrixBounds) NStrictlyInside(n uint) bool {
+	mb.M.panicOverSized(n)
+	return mb.locateN(n) == Inside
+}
+
+func (mb *MatrixBounds) locateN(n uint) Location {
+	r, c := mb.M.asRC(n)
+	return locate(len(mb.vertx), func(i int) (float64, float64) {
+		return float64(mb.verty[i]), float64(mb.vertx[i])
+	}, float64(r), float64(c))
 }
 
 // Returns the MatrixBounds bits currently set on
 func (m *MatrixBitSet) BoundsOfSets() (bounds *MatrixBounds, goodReturn bool) {
+	return m.boundsOfSets(m.FindMinC, m.FindMaxC)
+}
+
+// BoundsOfSets with the row scans for the min and max cols passed in
+// so ParallelRunner can band them
+func (m *MatrixBitSet) boundsOfSets(findMinC, findMaxC func(*MatrixBounds) (uint, uint, bool)) (bounds *MatrixBounds, goodReturn bool) {
 	minR, minC := m.R, m.C
 	goodReturn = false
+	if m.IsEmpty() {
+		return
+	}
 	if first, good := m.nextSet(0); good {
 		minR, minC = first/m.C, first%m.C
 		maxR, maxC := minR, minC
@@ -210,24 +271,35 @@ func (m *MatrixBitSet) BoundsOfSets() (bounds *MatrixBounds, goodReturn bool) {
 			last, _ := m.prevSet(lastIndex)
 			bounds.MaxR, bounds.MaxC = last/m.C, last%m.C
 			bounds.right, bounds.bottom = last, last
-			if bounds.Height() != 0 && bounds.MaxC < m.C-1 {
-				bounds.MaxC, bounds.right, _ = m.FindMaxC(bounds)
+			if bounds.Height() > 1 && bounds.MaxC < m.C-1 {
+				bounds.MaxC, bounds.right, _ = findMaxC(bounds)
 			}
 		}
 
-		if bounds.Height() != 0 && bounds.MinC != 0 {
-			bounds.MinC, bounds.left, _ = m.FindMinC(bounds)
+		if bounds.Height() > 1 && bounds.MinC != 0 {
+			bounds.MinC, bounds.left, _ = findMinC(bounds)
 		}
 		bounds.setup()
 	}
 	return
 }
 
-// Finds the minimum column between the passed rows
+// Finds the minimum column within the passed rows, MinR and MaxR included
 func (m *MatrixBitSet) FindMinC(bounds *MatrixBounds) (minC, n uint, good bool) {
+	return m.minCBetween(bounds.minCRows())
+}
+
+// The rows [r0, r1) FindMinC scans, MinR through MaxR
+func (mb *MatrixBounds) minCRows() (uint, uint) {
+	return mb.MinR, mb.MaxR + 1
+}
+
+// The first set col of each row in [r0, r1), an empty row counts
+// the first bit after it. Ties go to the topmost row
+func (m *MatrixBitSet) minCBetween(r0, r1 uint) (minC, n uint, good bool) {
 	minC = m.C
 
-	for row := bounds.MinR + 1; row < bounds.MaxR; row++ {
+	for row := r0; row < r1; row++ {
 		if mc, goodR := m.nextSet(m.index(row, 0)); goodR {
 			c := mc % m.C
 			if c < minC {
@@ -246,48 +318,51 @@ func (m *MatrixBitSet) FindMinC(bounds *MatrixBounds) (minC, n uint, good bool)
 	return
 }
 
-// Finds the largest col within the passed rows up to the largest row
-// Does not include the maxR
+// Finds the largest col within the passed rows, MinR and MaxR included
 func (m *MatrixBitSet) FindMaxC(bounds *MatrixBounds) (maxC, n uint, good bool) {
-	maxC = uint(0)
-	n = 0
-	for r := int(bounds.MaxR) - 1; r >= int(bounds.MinR); r-- {
-		// prev from the first column of the next row
-		// so we catch the last bit of our row being on
-		firstColNextRow := m.index(uint(r)+1, 0)
-		if mxc, goodC := m.prevSet(firstColNextRow); goodC {
-			mc := mxc % m.C
-			if mc > maxC {
-				maxC = mc
-				n = mxc
-				// Already as big as possible?
-				if mc == m.LastCol() {
-					break
-				}
+	return m.maxCBetween(bounds.maxCRows())
+}
+
+// The rows [r0, r1) FindMaxC scans, MinR through MaxR
+func (mb *MatrixBounds) maxCRows() (uint, uint) {
+	return mb.MinR, mb.MaxR + 1
+}
+
+// The last set col of each row in [r0, r1), an empty row counts
+// the last bit before it. Ties go to the topmost row
+func (m *MatrixBitSet) maxCBetween(r0, r1 uint) (maxC, n uint, good bool) {
+	for r := r0; r < r1; r++ {
+		// back from the last col of our row, which may be
+		// the last bit of the matrix, so test it first
+		lastCol := m.index(r, m.C-1)
+		mxc, goodC := lastCol, m.test(lastCol)
+		if !goodC {
+			mxc, goodC = m.prevSet(lastCol)
+		}
+		if !goodC {
+			continue
+		}
+		mc := mxc % m.C
+		if !good || mc > maxC {
+			maxC, n, good = mc, mxc, true
+			// Already as big as possible?
+			if mc == m.LastCol() {
+				break
 			}
-		} else {
-			break
 		}
 	}
-	good = maxC != uint(0)
 	return
 }
 
 // Extract just non interior positions
 func (m *MatrixBitSet) ExtractBorders() ([]MatrixPos, bool) {
-	borders := make([]MatrixPos, 0, 512)
-	for i, e := m.nextSet(0); e; i, e = m.NextSet(i + 1) {
-		if !m.internalN(i) {
-			borders = append(borders, m.NewPos(i))
-		}
-	}
+	borders := m.bordersBetween(0, m.R)
 	return borders, len(borders) != 0
 }
 
+// The pixel corners of a matrix, corner [r, c] is the upper left of pixel [r, c]
 type VertexSpace struct {
-	mb   *MatrixBitSet
-	cols uint
-	rows uint
+	mb *MatrixBitSet
 }
 
 func (vs *VertexSpace) PosFor(i uint) []VertexPos {
@@ -301,22 +376,40 @@ func (vs *VertexSpace) PosFor(i uint) []VertexPos {
 	return points
 }
 
+// One more row and col than the matrix, a corner for every pixel corner
+func (m *MatrixBitSet) VertexSpace() *VertexSpace {
+	return &VertexSpace{mb: m}
+}
+
+// Corners are strided by one more than the matrix's cols
+func (vs *VertexSpace) corners() (cols, rows uint) {
+	return vs.mb.C + 1, vs.mb.R + 1
+}
+
 type VertexPos struct {
 	v    *VertexSpace
 	r, c uint
 	i    uint
 }
 
+// The pixel this corner was generated from
 func (vp *VertexPos) ToMatrixPos() MatrixPos {
 	stride := vp.v.mb.C
 	return MatrixPos{vp.i / stride, vp.i % stride, stride}
 }
 
+// The corner itself, strided by one more than the matrix's cols
+func (vp *VertexPos) Corner() MatrixPos {
+	cols, _ := vp.v.corners()
+	return MatrixPos{vp.r, vp.c, cols}
+}
+
+// The corners of every set pixel on a border
 func (m *MatrixBitSet) ToVertexSpace() (*VertexSpace, []VertexPos) {
 	borders := make([]VertexPos, 0, 512)
-	vs := &VertexSpace{mb: m, cols: (m.C * 2) - 1, rows: (m.R * 2) - 1}
+	vs := m.VertexSpace()
 
-	for i, e := m.nextSet(0); e; i, e = m.NextSet(i + 1) {
+	for i, e := m.nextSet(0); e && i < m.R*m.C; i, e = m.nextSet(i + 1) {
 		if !m.internalN(i) {
 			borders = append(borders, vs.PosFor(i)...)
 		}
@@ -324,67 +417,6 @@ func (m *MatrixBitSet) ToVertexSpace() (*VertexSpace, []VertexPos) {
 	return vs, borders
 }
 
-type PosSet struct {
-	mutex sync.RWMutex
-	pos   map[string]MatrixPos
-}
-
-func NewPosSet(points []MatrixPos) *PosSet {
-	set := &PosSet{pos: make(map[string]MatrixPos)}
-	for _, pt := range points {
-		set.pos[pt.String()] = pt
-	}
-	return set
-}
-
-func (ps *PosSet) Add(mp MatrixPos) bool {
-	s := mp.String()
-	ps.mutex.Lock()
-	if _, found := ps.pos[s]; !found {
-		ps.pos[s] = mp
-		ps.mutex.Unlock()
-		return true
-	}
-	ps.mutex.Unlock()
-	return false
-}
-
-func (ps *PosSet) Remove(mp MatrixPos) bool {
-	s := mp.String()
-	ps.mutex.Lock()
-	_, found := ps.pos[s]
-	if found {
-		delete(ps.pos, s)
-	}
-	ps.mutex.Unlock()
-	return found
-}
-
-func (ps *PosSet) Contains(mp MatrixPos) bool {
-	s := mp.String()
-	ps.mutex.RLock()
-	_, found := ps.pos[s]
-	ps.mutex.RUnlock()
-	return found
-}
-
-func (ps *PosSet) IsEmpty() bool {
-	ps.mutex.RLock()
-	count := len(ps.pos)
-	ps.mutex.RUnlock()
-	return count == 0
-}
-
-func (ps *PosSet) Points() []MatrixPos {
-	ps.mutex.RLock()
-	points := make([]MatrixPos, 0, len(ps.pos))
-	for _, v := range ps.pos {
-		points = append(points, v)
-	}
-	ps.mutex.RUnlock()
-	return points
-}
-
 type LinearRing []MatrixPos
 
 type Polygon struct {
@@ -395,22 +427,26 @@ type Polygon struct {
 // Assuming this matrix contains filled in areas, this function
 // will grab their boundaries including holes. It will also grab
 // polygons within a hole of an outer polygon and will nest any levels deep.
-func (m *MatrixBitSet) ExtractAllPolygons() ([]*Polygon, bool) {
+// Pass WithTracer to pick the border follower, TraceShell by default
+func (m *MatrixBitSet) ExtractAllPolygons(opts ...PolygonOption) ([]*Polygon, bool) {
+	if newPolygonOptions(opts).tracer == SuzukiAbeTracer {
+		return m.suzukiAbePolygons()
+	}
 	borders, ok := m.ExtractBorders()
 	if !ok {
 		// No set bits
 		return []*Polygon{}, true
 	}
 	m2 := NewMatrixBitSet(m.C, m.R)
-	sort.Sort(ByRows(borders))
+	borderSet := NewPosSetFor(m)
 	for _, mp := range borders {
 		m2.Set(mp.Both())
+		borderSet.Add(mp)
 	}
-	borderSet := NewPosSet(borders)
 	polygons := make([]*Polygon, 0, 128)
 
-	for {
-		if polygon, erase, ok := m2.ExtractPolygon(borders[0]); ok {
+	for start, more := borderSet.First(); more; start, more = borderSet.First() {
+		if polygon, erase, ok := m2.ExtractPolygon(start); ok {
 			// a nil polygon means it was shorter than 5 points
 			// a triangle will have many points stair stepping diagonally
 			// a rect will have at least 4 + the origin == 5
@@ -424,12 +460,6 @@ func (m *MatrixBitSet) ExtractAllPolygons() ([]*Polygon, bool) {
 		} else {
 			return nil, false
 		}
-		if !borderSet.IsEmpty() {
-			borders = borderSet.Points()
-			sort.Sort(ByRows(borders))
-		} else {
-			break
-		}
 	}
 	return polygons, true
 }
@@ -733,6 +763,10 @@ func (m *MatrixBitSet) jarvisHull(points []MatrixPos, bounds *MatrixBounds) ([]M
 // is cartesian (origin lower left)
 // this flips the slopes, so > 0 is counter clockwise
 func (m *MatrixBitSet) Orient(p, q, r MatrixPos) int {
+	return orient(p, q, r)
+}
+
+func orient(p, q, r MatrixPos) int {
 	qpRow := q.Row_i() - p.Row_i()
 	rqCol := r.Col_i() - q.Col_i()
 	qpCol := q.Col_i() - p.Col_i()
@@ -762,10 +796,47 @@ func (m *MatrixBitSet) FormatWord(w uint64) string {
 	return b.String()
 }
 
-// Flip every bit in place
+// Flip every bit in place, the padding past the last bit stays clear
 func (m *MatrixBitSet) Invert() *MatrixBitSet {
+	total := m.R * m.C
 	for i, w := range m.B {
-		m.B[i] = ^w
+		m.B[i] = ^w & rowMask(total, i)
+	}
+	return m
+}
+
+// Keeps only the bits also on in other, which must be the same size
+func (m *MatrixBitSet) And(other *MatrixBitSet) *MatrixBitSet {
+	m.panicOtherSize(other)
+	for i, w := range other.B {
+		m.B[i] &= w
+	}
+	return m
+}
+
+// Turns on the bits on in other, which must be the same size
+func (m *MatrixBitSet) Or(other *MatrixBitSet) *MatrixBitSet {
+	m.panicOtherSize(other)
+	for i, w := range other.B {
+		m.B[i] |= w
+	}
+	return m
+}
+
+// Flips the bits on in other, which must be the same size
+func (m *MatrixBitSet) Xor(other *MatrixBitSet) *MatrixBitSet {
+	m.panicOtherSize(other)
+	for i, w := range other.B {
+		m.B[i] ^= w
+	}
+	return m
+}
+
+// Turns off the bits on in other, which must be the same size
+func (m *MatrixBitSet) AndNot(other *MatrixBitSet) *MatrixBitSet {
+	m.panicOtherSize(other)
+	for i, w := range other.B {
+		m.B[i] &^= w
 	}
 	return m
 }
@@ -813,8 +884,8 @@ func (m *MatrixBitSet) prevSet(i uint) (uint, bool) {
 	// if not the low bit, test for others to its right
 	// otherwise, go to the previous word
 	if i&(wordSize-1) > 0 {
-		// mask off i and above
-		w = w & ^(allBits << i)
+		// mask off i and above, within this word
+		w = w & ^(allBits << (i & mask))
 		if w != 0 {
 			// return the highbit (previous)
 			return uint(x)*wordSize + (mask - uint(bits.LeadingZeros64(w))), true
@@ -831,11 +902,18 @@ func (m *MatrixBitSet) prevSet(i uint) (uint, bool) {
 	return 0, false
 }
 
+// Callers bounds check [r, c] first, on a matrix without rows or
+// cols every [r, c] is past it, so the index is never used
 func (m *MatrixBitSet) index(r, c uint) uint {
 	return (r * m.C) + c
 }
 
+// Every n is past a matrix without cols, panics like TestN rather than
+// dividing by zero
 func (m *MatrixBitSet) asRC(n uint) (uint, uint) {
+	if m.C == 0 {
+		m.panicOverSized(n)
+	}
 	return n / m.C, n % m.C
 }
 
@@ -869,13 +947,19 @@ func (m *MatrixBitSet) clear(i uint) *MatrixBitSet {
 }
 
 func (m *MatrixBitSet) panicPastMatrix(r, c uint) {
-	if r >= m.R || c >= m.C {
-		panic(fmt.Sprintf("[%d, %d] exceeds matrix bounds %d x %d", r, c, m.R, m.C))
+	if e := m.checkPastMatrix(r, c); e != nil {
+		panic(e.Error())
 	}
 }
 
 func (m *MatrixBitSet) panicOverSized(i uint) {
-	if i >= m.R*m.C {
-		panic(fmt.Sprintf("[%d] exceeds matrix bounds %d", i, m.R*m.C))
+	if e := m.checkOverSized(i); e != nil {
+		panic(e.Error())
+	}
+}
+
+func (m *MatrixBitSet) panicOtherSize(other *MatrixBitSet) {
+	if m.R != other.R || m.C != other.C {
+		panic(fmt.Sprintf("matrix %d x %d doesn't match %d x %d", other.R, other.C, m.R, m.C))
 	}
 }
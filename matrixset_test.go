@@ -1,9 +1,11 @@
 package matrixbitset
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"image/png"
+	"math/rand"
 	"os"
 	"sort"
 	"testing"
@@ -252,3 +254,182 @@ func TestExtractPolygon(t *testing.T) {
 		}
 	}
 }
+
+func TestNewMatrixBitSetChecked(t *testing.T) {
+	if _, err := NewMatrixBitSetChecked(0, 10); err == nil {
+		t.Error("Expected an error for 0 cols")
+	}
+	if _, err := NewMatrixBitSetChecked(10, 0); err == nil {
+		t.Error("Expected an error for 0 rows")
+	}
+	if _, err := NewMatrixBitSetChecked(1<<40, 1<<40); err == nil {
+		t.Error("Expected an error for overflowing dimensions")
+	}
+	if _, err := NewMatrixBitSetChecked(1<<26, 1<<26); err == nil {
+		t.Error("Expected an error for 2^52 bits")
+	}
+	m, err := NewMatrixBitSetChecked(65, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.B) != 4 {
+		t.Errorf("Expected 4 words for 3 x 65, received %d", len(m.B))
+	}
+}
+
+func TestDegenerateMatrices(t *testing.T) {
+	empty := NewMatrixBitSet(5, 0)
+	if !empty.IsEmpty() {
+		t.Error("Expected 0 x 5 to be empty")
+	}
+	if empty.LastRow() != 0 || empty.LastCol() != 4 {
+		t.Errorf("Expected last [0, 4], received [%d, %d]", empty.LastRow(), empty.LastCol())
+	}
+	if _, ok := empty.BoundsOfSets(); ok {
+		t.Error("Expected no bounds for an empty matrix")
+	}
+
+	one := NewMatrixBitSet(1, 1)
+	if one.LastRow() != 0 || one.LastCol() != 0 {
+		t.Errorf("Expected last [0, 0], received [%d, %d]", one.LastRow(), one.LastCol())
+	}
+	if _, ok := one.BoundsOfSets(); ok {
+		t.Error("Expected no bounds for a clear 1 x 1")
+	}
+	one.Set(0, 0)
+	bounds, ok := one.BoundsOfSets()
+	if !ok {
+		t.Fatal("No bounds returned for 1 x 1")
+	}
+	if bounds.MinR != 0 || bounds.MinC != 0 || bounds.MaxR != 0 || bounds.MaxC != 0 {
+		t.Errorf("Expected bounds [0, 0] - [0, 0], received [%d, %d] - [%d, %d]", bounds.MinR, bounds.MinC, bounds.MaxR, bounds.MaxC)
+	}
+	pos := one.NewPos(0)
+	if down := pos.Down(one.R); down.Valid() {
+		t.Errorf("Expected no Down from %v, received %v", pos, down)
+	}
+	if right := pos.Right(one.C); right.Valid() {
+		t.Errorf("Expected no Right from %v, received %v", pos, right)
+	}
+	if down := pos.Down(0); down.Valid() {
+		t.Errorf("Expected no Down with 0 rows, received %v", down)
+	}
+
+	noCols := NewMatrixBitSet(0, 5)
+	if mp := noCols.NewPos(3); mp.Valid() {
+		t.Errorf("Expected no position in 5 x 0, received %v", mp)
+	}
+	if _, err := noCols.TryTest(0, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected [0, 0] to be past 5 x 0, received %v", err)
+	}
+	if _, err := empty.TryTest(0, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Expected [0, 0] to be past 0 x 5, received %v", err)
+	}
+	if !one.Test(0, 0) || one.index(0, 0) != 0 {
+		t.Error("Expected [0, 0] to be index 0 and set in 1 x 1")
+	}
+}
+
+func TestBoundsOfSetsEdgeRows(t *testing.T) {
+	tests := []struct {
+		fixture                string
+		minR, minC, maxR, maxC uint
+	}{
+		// the widest row is the last
+		{`
+		....##....
+		.....#....
+		.#######..`, 0, 1, 2, 7},
+		// only two rows, nothing between them to scan
+		{`
+		....##....
+		..######..`, 0, 2, 1, 7},
+		// every bit in col 0
+		{`
+		..........
+		#.........
+		#.........`, 1, 0, 2, 0},
+		// the last bit of the matrix on
+		{`
+		...#......
+		.........#`, 0, 3, 1, 9},
+	}
+	for _, test := range tests {
+		m, err := ParseASCII(test.fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bounds, ok := m.BoundsOfSets()
+		if !ok {
+			t.Fatalf("No bounds for\n%s", m.FormatASCII())
+		}
+		if bounds.MinR != test.minR || bounds.MinC != test.minC || bounds.MaxR != test.maxR || bounds.MaxC != test.maxC {
+			t.Errorf("Expected [%d, %d]-[%d, %d], received [%d, %d]-[%d, %d] for\n%s", test.minR, test.minC, test.maxR, test.maxC,
+				bounds.MinR, bounds.MinC, bounds.MaxR, bounds.MaxC, m.FormatASCII())
+		}
+		if !m.TestN(bounds.left) || bounds.left%m.C != bounds.MinC || !m.TestN(bounds.right) || bounds.right%m.C != bounds.MaxC {
+			t.Errorf("Expected left, right on in cols %d, %d, received %v, %v", bounds.MinC, bounds.MaxC, m.NewPos(bounds.left), m.NewPos(bounds.right))
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(26))
+	for trial := 0; trial < 50; trial++ {
+		m := randomMatrix(rnd, uint(1+rnd.Intn(70)), uint(1+rnd.Intn(20)), rnd.Float64()*0.1)
+		expected, found := bruteBBox(m)
+		bounds, ok := m.BoundsOfSets()
+		if ok != found || ok && expected != [4]uint{bounds.MinC, bounds.MinR, bounds.Width(), bounds.Height()} {
+			t.Errorf("Expected bbox %v, received %+v for\n%s", expected, bounds, m.FormatASCII())
+		}
+	}
+}
+
+func TestPrevSetPastFirstWord(t *testing.T) {
+	m := NewMatrixBitSet(70, 3)
+	m.SetN(65).SetN(100).SetN(130)
+	if prev, ok := m.PrevSet(100); !ok || prev != 65 {
+		t.Errorf("Expected 65 before 100, received %d %v", prev, ok)
+	}
+	if prev, ok := m.PrevSet(129); !ok || prev != 100 {
+		t.Errorf("Expected 100 before 129, received %d %v", prev, ok)
+	}
+}
+
+func TestToVertexSpaceLastBit(t *testing.T) {
+	m, _ := ParseASCII(`
+		...
+		.##
+	`)
+	vs, corners := m.ToVertexSpace()
+	if cols, rows := vs.corners(); cols != 4 || rows != 3 {
+		t.Errorf("Expected a 3 x 4 space, received %d x %d", rows, cols)
+	}
+	if len(corners) != 8 {
+		t.Errorf("Expected 8 corners, received %d", len(corners))
+	}
+	if last := corners[len(corners)-1]; last.Corner() != NewMatrixPos(2, 2, 4) || last.ToMatrixPos() != NewMatrixPos(1, 2, 3) {
+		t.Errorf("Unexpected last corner %v of %v", last.Corner(), last.ToMatrixPos())
+	}
+}
+
+func TestJarvisHullOnBoundsEdges(t *testing.T) {
+	// every border pixel sits on the bounds' edges, so only the extremes are kept
+	m, _ := ParseASCII(`
+		.....#.....
+		....###....
+		...#####...
+		..#######..
+		...#####...
+		....###....
+		.....#.....
+	`)
+	hull, ok := m.JarvisHullOfSets()
+	expected := []MatrixPos{m.NewPos(m.index(3, 2)), m.NewPos(m.index(0, 5)), m.NewPos(m.index(3, 8)), m.NewPos(m.index(6, 5)), m.NewPos(m.index(3, 2))}
+	if !ok || len(hull) != len(expected) {
+		t.Fatalf("Expected the 4 extremes, received %v", hull)
+	}
+	for i := range expected {
+		if hull[i] != expected[i] {
+			t.Errorf("Expected %v at %d, received %v", expected[i], i, hull[i])
+		}
+	}
+}
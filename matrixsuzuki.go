@@ -0,0 +1,198 @@
+package matrixbitset
+
+// Topological Structural Analysis of Digitized Binary Images by Border Following
+// Suzuki, S. and Abe, K., CVGIP 30 1, pp 32-46 (1985)
+// Unlike TraceShell it never clears bits to mark its progress, it labels
+// a padded copy of the matrix, so one pixel wide necks, spurs and diagonal
+// only connections are walked through rather than stranded
+
+// A border found by suzukiAbe, parent indexes the border enclosing it
+// or is -1 when it sits directly in the background
+type suzukiBorder struct {
+	outer  bool
+	parent int
+	ring   LinearRing
+}
+
+// Clockwise as displayed, starting East
+var suzukiSteps = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+
+func (m *MatrixBitSet) suzukiAbePolygons() ([]*Polygon, bool) {
+	borders := m.suzukiAbe()
+	polygons := make([]*Polygon, 0, 128)
+	owner := make(map[int]*Polygon)
+	for nbd, b := range borders {
+		if b.outer {
+			polygon := &Polygon{Outer: b.ring, Holes: make([]LinearRing, 0)}
+			owner[nbd] = polygon
+			polygons = append(polygons, polygon)
+		}
+	}
+	// a hole always follows its outer border, so owner is populated
+	for _, b := range borders {
+		if !b.outer {
+			owner[b.parent].Holes = append(owner[b.parent].Holes, b.ring)
+		}
+	}
+	return polygons, true
+}
+
+// Every border in raster order of their starting pixel
+func (m *MatrixBitSet) suzukiAbe() []suzukiBorder {
+	stride := int(m.C) + 2
+	f := make([]int32, stride*(int(m.R)+2))
+	for i, e := m.nextSet(0); e && i < m.R*m.C; i, e = m.nextSet(i + 1) {
+		r, c := m.asRC(i)
+		f[(int(r)+1)*stride+int(c)+1] = 1
+	}
+
+	// NBD 1 is the frame, a hole border, NBD n is borders[n-2]
+	borders := make([]suzukiBorder, 0, 128)
+	isOuter := func(nbd int32) bool {
+		return nbd > 1 && borders[nbd-2].outer
+	}
+	parentOf := func(nbd int32) int {
+		if nbd <= 1 {
+			return -1
+		}
+		return borders[nbd-2].parent
+	}
+
+	for i := 1; i <= int(m.R); i++ {
+		lnbd := int32(1)
+		for j := 1; j <= int(m.C); j++ {
+			p := i*stride + j
+			var from int
+			var outer bool
+			if f[p] == 1 && f[p-1] == 0 {
+				outer, from = true, 4
+			} else if f[p] >= 1 && f[p+1] == 0 {
+				outer, from = false, 0
+				if f[p] > 1 {
+					lnbd = f[p]
+				}
+			} else {
+				if f[p] != 0 && f[p] != 1 {
+					lnbd = abs32(f[p])
+				}
+				continue
+			}
+
+			// the new border's parent comes from the last border crossed
+			parent := -1
+			if outer == isOuter(lnbd) {
+				parent = parentOf(lnbd)
+			} else if lnbd > 1 {
+				parent = int(lnbd) - 2
+			}
+			nbd := int32(len(borders) + 2)
+			ring := m.suzukiFollow(f, stride, i, j, from, nbd)
+			// outer rings counter clockwise, holes clockwise, like TraceShell's
+			if area := ring.Area2(); (outer && area < 0) || (!outer && area > 0) {
+				ring.reverse()
+			}
+			borders = append(borders, suzukiBorder{outer: outer, parent: parent, ring: ring})
+
+			if f[p] != 1 {
+				lnbd = abs32(f[p])
+			}
+		}
+	}
+	return borders
+}
+
+// Steps 3.1 through 3.5, labelling the border with nbd as it goes
+// from is the direction of the background pixel that found (i, j)
+func (m *MatrixBitSet) suzukiFollow(f []int32, stride, i, j, from int, nbd int32) LinearRing {
+	at := func(r, c int) int {
+		return r*stride + c
+	}
+	start := at(i, j)
+	points := make([]MatrixPos, 0, 128)
+	points = append(points, NewMatrixPos(uint(i-1), uint(j-1), m.C))
+
+	// 3.1 clockwise from the background pixel for any non zero pixel
+	first := -1
+	for k := 0; k < 8; k++ {
+		d := (from + k) % 8
+		if f[at(i+suzukiSteps[d][0], j+suzukiSteps[d][1])] != 0 {
+			first = d
+			break
+		}
+	}
+	if first < 0 {
+		// isolated pixel
+		f[start] = -nbd
+		return simplifyRing(points)
+	}
+
+	// 3.2
+	r2, c2 := i+suzukiSteps[first][0], j+suzukiSteps[first][1]
+	r3, c3 := i, j
+	for {
+		// 3.3 counter clockwise from just past (r2, c2) around (r3, c3)
+		back := suzukiDir(r2-r3, c2-c3)
+		eastClear := false
+		var r4, c4 int
+		for k := 1; k <= 8; k++ {
+			d := (back - k + 16) % 8
+			rr, cc := r3+suzukiSteps[d][0], c3+suzukiSteps[d][1]
+			if f[at(rr, cc)] != 0 {
+				r4, c4 = rr, cc
+				break
+			}
+			if d == 0 {
+				eastClear = true
+			}
+		}
+		// 3.4
+		p3 := at(r3, c3)
+		if eastClear {
+			f[p3] = -nbd
+		} else if f[p3] == 1 {
+			f[p3] = nbd
+		}
+		// 3.5
+		if at(r4, c4) == start && r3 == i+suzukiSteps[first][0] && c3 == j+suzukiSteps[first][1] {
+			break
+		}
+		points = append(points, NewMatrixPos(uint(r4-1), uint(c4-1), m.C))
+		r2, c2 = r3, c3
+		r3, c3 = r4, c4
+	}
+	return simplifyRing(points)
+}
+
+func suzukiDir(dr, dc int) int {
+	for d, step := range suzukiSteps {
+		if step[0] == dr && step[1] == dc {
+			return d
+		}
+	}
+	return 0
+}
+
+// Drops the points that continue in the same direction and closes the ring
+func simplifyRing(points []MatrixPos) LinearRing {
+	n := len(points)
+	ring := make(LinearRing, 0, n+1)
+	for k := 0; k < n; k++ {
+		prev, cur, next := points[(k+n-1)%n], points[k], points[(k+1)%n]
+		if k == 0 || orient(prev, cur, next) != 0 || !sameWay(prev, cur, next) {
+			ring = append(ring, cur)
+		}
+	}
+	return append(ring, ring[0])
+}
+
+// cur is between prev and next, not a spur doubling back
+func sameWay(prev, cur, next MatrixPos) bool {
+	return (cur.Row_i()-prev.Row_i())*(next.Row_i()-cur.Row_i())+(cur.Col_i()-prev.Col_i())*(next.Col_i()-cur.Col_i()) > 0
+}
+
+func abs32(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
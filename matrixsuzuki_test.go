@@ -0,0 +1,180 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+// '#' is set, anything else is clear
+func shapeFromRows(rows []string) *MatrixBitSet {
+	m := NewMatrixBitSet(uint(len(rows[0])), uint(len(rows)))
+	for r, row := range rows {
+		for c, ch := range row {
+			if ch == '#' {
+				m.Set(uint(r), uint(c))
+			}
+		}
+	}
+	return m
+}
+
+var suzukiCorpus = []struct {
+	name     string
+	rows     []string
+	polygons int
+	holes    int
+}{
+	{"single pixel", []string{
+		"...",
+		".#.",
+		"...",
+	}, 1, 0},
+	{"whole matrix", []string{
+		"###",
+		"###",
+	}, 1, 0},
+	{"one pixel line", []string{
+		"......",
+		".####.",
+		"......",
+	}, 1, 0},
+	{"diagonal", []string{
+		"#....",
+		".#...",
+		"..#..",
+		"...#.",
+	}, 1, 0},
+	{"one pixel neck", []string{
+		"###...###",
+		"###...###",
+		"#########",
+		"###...###",
+	}, 1, 0},
+	{"spur", []string{
+		".......",
+		".###...",
+		".######",
+		".###...",
+	}, 1, 0},
+	{"diagonal only blobs", []string{
+		"##....",
+		"##....",
+		"..##..",
+		"..##..",
+	}, 1, 0},
+	{"one pixel wide ring", []string{
+		"#####",
+		"#...#",
+		"#...#",
+		"#####",
+	}, 1, 1},
+	{"holes touching diagonally", []string{
+		"#####",
+		"#.###",
+		"##.##",
+		"#####",
+	}, 1, 2},
+	{"checkerboard", []string{
+		"#####",
+		"#.#.#",
+		"##.##",
+		"#.#.#",
+		"#####",
+	}, 1, 5},
+	{"island in a hole", []string{
+		"#######",
+		"#.....#",
+		"#.###.#",
+		"#.#.#.#",
+		"#.###.#",
+		"#.....#",
+		"#######",
+	}, 2, 2},
+}
+
+// Set pixels with a clear (or missing) 4 neighbour
+func suzukiBorderPixels(m *MatrixBitSet) map[MatrixPos]bool {
+	border := make(map[MatrixPos]bool)
+	for i, e := m.nextSet(0); e; i, e = m.nextSet(i + 1) {
+		pos := m.NewPos(i)
+		if m.testPos(pos.Up())+m.testPos(pos.Down(m.R))+m.testPos(pos.Left())+m.testPos(pos.Right(m.C)) < 4 {
+			border[pos] = true
+		}
+	}
+	return border
+}
+
+func ringPixels(ring LinearRing, into map[MatrixPos]bool) {
+	for i := 0; i+1 < len(ring); i++ {
+		r, c := ring[i].Row_i(), ring[i].Col_i()
+		for {
+			into[NewMatrixPos(uint(r), uint(c), ring[i].stride)] = true
+			if r == ring[i+1].Row_i() && c == ring[i+1].Col_i() {
+				break
+			}
+			r, c = r+sign(ring[i+1].Row_i()-r), c+sign(ring[i+1].Col_i()-c)
+		}
+	}
+	if len(ring) == 1 {
+		into[ring[0]] = true
+	}
+}
+
+func TestSuzukiAbeCorpus(t *testing.T) {
+	for _, shape := range suzukiCorpus {
+		m := shapeFromRows(shape.rows)
+		polygons, ok := m.ExtractAllPolygons(WithTracer(SuzukiAbeTracer))
+		if !ok {
+			t.Errorf("%s: ExtractAllPolygons failed", shape.name)
+			continue
+		}
+		holes := 0
+		traced := make(map[MatrixPos]bool)
+		for _, p := range polygons {
+			holes += len(p.Holes)
+			for _, ring := range append([]LinearRing{p.Outer}, p.Holes...) {
+				if ring[0] != ring[len(ring)-1] {
+					t.Errorf("%s: ring %v is not closed", shape.name, ring)
+				}
+				ringPixels(ring, traced)
+			}
+			if p.Outer.Area2() < 0 {
+				t.Errorf("%s: expected a counter clockwise outer ring %v", shape.name, p.Outer)
+			}
+		}
+		if len(polygons) != shape.polygons || holes != shape.holes {
+			t.Errorf("%s: expected %d polygons %d holes, received %d, %d", shape.name, shape.polygons, shape.holes, len(polygons), holes)
+		}
+		border := suzukiBorderPixels(m)
+		for pos := range border {
+			if !traced[pos] {
+				t.Errorf("%s: border pixel %v was not traced", shape.name, pos)
+			}
+		}
+		for pos := range traced {
+			if !m.Test(pos.Both()) {
+				t.Errorf("%s: traced %v which is not set", shape.name, pos)
+			}
+		}
+	}
+}
+
+func TestSuzukiAbeMatchesShell(t *testing.T) {
+	m := NewMatrixBitSet(700, 700)
+	m.Fill(100, 100, 500, 500)
+	m.Drain(150, 150, 25, 25)
+	m.Fill(150, 50, 50, 50)
+	m.Fill(200, 600, 50, 50)
+	shell, _ := m.ExtractAllPolygons()
+	polygons, ok := m.ExtractAllPolygons(WithTracer(SuzukiAbeTracer))
+	if !ok || len(polygons) != len(shell) {
+		t.Fatalf("Expected %d polygons, received %d", len(shell), len(polygons))
+	}
+	if len(polygons[0].Holes) != len(shell[0].Holes) {
+		t.Errorf("Expected %d holes, received %d", len(shell[0].Holes), len(polygons[0].Holes))
+	}
+	// concave corners are cut diagonally, so only the extremes agree
+	expected, got := NewMatrixBounds(m, shell[0].Outer), NewMatrixBounds(m, polygons[0].Outer)
+	if expected.MinR != got.MinR || expected.MinC != got.MinC || expected.MaxR != got.MaxR || expected.MaxC != got.MaxC {
+		t.Errorf("Expected outer extremes %+v, received %+v", expected, got)
+	}
+}
@@ -0,0 +1,176 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Vector drawings of a matrix and what was extracted from it, a layer at
+// a time, each with its own style. Pixel [r, c] covers the square from
+// (c, r) to (c+1, r+1) before scaling, so pixel positions, like TraceShell
+// vertexes, are drawn at pixel centers, while VertexSpace positions, whose
+// stride is one more than the matrix's cols, are drawn at pixel corners
+type SVG struct {
+	M      *MatrixBitSet
+	Scale  float64
+	layers []string
+}
+
+// Attributes of a layer, zero values are left out except Fill, which
+// is none unless given. Fill colors label text, StrokeWidth is unscaled
+type SVGStyle struct {
+	Fill        string
+	Stroke      string
+	StrokeWidth float64
+	Opacity     float64
+	FontSize    float64
+}
+
+// scale is SVG units per pixel
+func NewSVG(m *MatrixBitSet, scale float64) *SVG {
+	return &SVG{M: m, Scale: scale}
+}
+
+// The set pixels, as one path of row runs
+func (s *SVG) AddMask(style SVGStyle) *SVG {
+	var d strings.Builder
+	total := s.M.R * s.M.C
+	for i, ok := s.M.nextSet(0); ok && i < total; {
+		r, c := s.M.asRC(i)
+		end := i
+		for end < total && end < s.M.index(r+1, 0) && s.M.test(end) {
+			end++
+		}
+		fmt.Fprintf(&d, "M%s %sh%sv%sh-%sz", s.num(float64(c)), s.num(float64(r)),
+			s.num(float64(end-i)), s.num(1), s.num(float64(end-i)))
+		i, ok = s.M.nextSet(end)
+	}
+	return s.addPath(d.String(), style)
+}
+
+// Filled even-odd, so holes show through
+func (s *SVG) AddPolygons(polygons []*Polygon, style SVGStyle) *SVG {
+	for _, p := range polygons {
+		var d strings.Builder
+		for _, ring := range p.rings() {
+			d.WriteString(s.ringPath(ring))
+		}
+		s.addPath(d.String(), style)
+	}
+	return s
+}
+
+// The convex hull JarvisHullOfSets finds, nothing when the matrix is empty
+func (s *SVG) AddHull(style SVGStyle) *SVG {
+	hull, ok := s.M.JarvisHullOfSets()
+	if !ok {
+		return s
+	}
+	return s.addPath(s.ringPath(hull), style)
+}
+
+// Rectangles around the pixels each bounds spans
+func (s *SVG) AddBounds(bounds []*MatrixBounds, style SVGStyle) *SVG {
+	var b strings.Builder
+	for _, mb := range bounds {
+		fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s"/>`,
+			s.num(float64(mb.MinC)), s.num(float64(mb.MinR)), s.num(float64(mb.Width())), s.num(float64(mb.Height())))
+	}
+	s.layers = append(s.layers, s.group(style, b.String()))
+	return s
+}
+
+// Text centered on the pixel [r, c]
+func (s *SVG) AddLabel(r, c float64, text string, style SVGStyle) *SVG {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<text x="%s" y="%s" text-anchor="middle" dominant-baseline="central">`,
+		s.num(c+0.5), s.num(r+0.5))
+	template.HTMLEscape(&b, []byte(text))
+	b.WriteString("</text>")
+	s.layers = append(s.layers, s.group(style, b.String()))
+	return s
+}
+
+// The whole document, the layers in the order they were added
+func (s *SVG) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	width, height := s.num(float64(s.M.C)), s.num(float64(s.M.R))
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`,
+		width, height, width, height)
+	b.WriteString("\n")
+	for _, layer := range s.layers {
+		b.WriteString(layer)
+		b.WriteString("\n")
+	}
+	b.WriteString("</svg>\n")
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (s *SVG) String() string {
+	var b strings.Builder
+	s.WriteTo(&b)
+	return b.String()
+}
+
+func (s *SVG) addPath(d string, style SVGStyle) *SVG {
+	if d == "" {
+		return s
+	}
+	s.layers = append(s.layers, s.group(style, fmt.Sprintf(`<path fill-rule="evenodd" d="%s"/>`, d)))
+	return s
+}
+
+func (s *SVG) ringPath(ring []MatrixPos) string {
+	var d strings.Builder
+	for i, mp := range ring {
+		x, y := float64(mp.c), float64(mp.r)
+		if mp.stride != s.M.C+1 {
+			x, y = x+0.5, y+0.5
+		}
+		if i == 0 {
+			d.WriteString("M")
+		} else {
+			d.WriteString("L")
+		}
+		fmt.Fprintf(&d, "%s %s", s.num(x), s.num(y))
+	}
+	if len(ring) > 0 {
+		d.WriteString("Z")
+	}
+	return d.String()
+}
+
+func (s *SVG) group(style SVGStyle, body string) string {
+	var b strings.Builder
+	b.WriteString("<g")
+	fill := style.Fill
+	if fill == "" {
+		fill = "none"
+	}
+	fmt.Fprintf(&b, ` fill="%s"`, template.HTMLEscapeString(fill))
+	if style.Stroke != "" {
+		fmt.Fprintf(&b, ` stroke="%s"`, template.HTMLEscapeString(style.Stroke))
+	}
+	if style.StrokeWidth > 0 {
+		fmt.Fprintf(&b, ` stroke-width="%s"`, strconv.FormatFloat(style.StrokeWidth, 'f', -1, 64))
+	}
+	if style.Opacity > 0 {
+		fmt.Fprintf(&b, ` opacity="%s"`, strconv.FormatFloat(style.Opacity, 'f', -1, 64))
+	}
+	if style.FontSize > 0 {
+		fmt.Fprintf(&b, ` font-size="%s"`, strconv.FormatFloat(style.FontSize, 'f', -1, 64))
+	}
+	b.WriteString(">")
+	b.WriteString(body)
+	b.WriteString("</g>")
+	return b.String()
+}
+
+// A pixel coordinate scaled to SVG units
+func (s *SVG) num(v float64) string {
+	return strconv.FormatFloat(v*s.Scale, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package matrixbitset
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestSVGLayers(t *testing.T) {
+	m, _ := ParseASCII(`
+		.....
+		.###.
+		.#.#.
+		.###.
+		.....
+	`)
+	polygons, _ := m.ExtractCrackPolygons()
+	bounds, _ := m.BoundsOfSets()
+	svg := NewSVG(m, 10).
+		AddMask(SVGStyle{Fill: "#ccc"}).
+		AddPolygons(polygons, SVGStyle{Fill: "red", Stroke: "black", StrokeWidth: 0.5, Opacity: 0.4}).
+		AddHull(SVGStyle{Stroke: "blue"}).
+		AddBounds([]*MatrixBounds{bounds}, SVGStyle{Stroke: "green"}).
+		AddLabel(2, 2, "a < b & c", SVGStyle{Fill: "black", FontSize: 8})
+	out := svg.String()
+
+	// well formed all the way through
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err.Error() != "EOF" {
+				t.Fatalf("Malformed SVG %v\n%s", err, out)
+			}
+			break
+		}
+	}
+	for _, expected := range []string{
+		`width="50" height="50" viewBox="0 0 50 50"`,
+		// the mask's first row run
+		`M10 10h30v10h-30z`,
+		// crack polygons sit on pixel corners, the hole too
+		`fill-rule="evenodd"`,
+		`fill="red" stroke="black" stroke-width="0.5" opacity="0.4"`,
+		`<rect x="10" y="10" width="30" height="30"/>`,
+		`<text x="25" y="25"`,
+		`a &lt; b &amp; c`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "<g ") != 5 {
+		t.Errorf("Expected 5 layers\n%s", out)
+	}
+}
+
+func TestSVGPixelCenters(t *testing.T) {
+	m := NewMatrixBitSet(4, 4)
+	ring := LinearRing{NewMatrixPos(1, 1, 4), NewMatrixPos(1, 2, 4), NewMatrixPos(2, 2, 4), NewMatrixPos(1, 1, 4)}
+	out := NewSVG(m, 2).AddPolygons([]*Polygon{{Outer: ring}}, SVGStyle{}).String()
+	if !strings.Contains(out, `d="M3 3L5 3L5 5L3 3Z"`) || !strings.Contains(out, `fill="none"`) {
+		t.Errorf("Expected pixel vertexes at their centers\n%s", out)
+	}
+	if strings.Contains(NewSVG(m, 1).AddHull(SVGStyle{}).AddMask(SVGStyle{}).String(), "<g") {
+		t.Error("Expected nothing drawn for an empty matrix")
+	}
+}
@@ -0,0 +1,215 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Just enough TIFF for bilevel images held in a single strip,
+// uncompressed or Group 4. Set bits are black
+
+type TIFFCompression uint16
+
+const (
+	TIFFUncompressed TIFFCompression = 1
+	TIFFGroup4       TIFFCompression = 4
+)
+
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffPhotometric     = 262
+	tiffFillOrder       = 266
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+
+	tiffShort = 3
+	tiffLong  = 4
+
+	tiffWhiteIsZero = 0
+	tiffBlackIsZero = 1
+)
+
+// Little endian, WhiteIsZero, the strip right after the header
+func (m *MatrixBitSet) WriteTIFF(w io.Writer, compression TIFFCompression) error {
+	var strip []byte
+	switch compression {
+	case TIFFUncompressed:
+		strip = m.packRows(true)
+	case TIFFGroup4:
+		strip = m.EncodeG4()
+	default:
+		return fmt.Errorf("tiff: compression %d isn't supported", compression)
+	}
+
+	entries := []struct {
+		tag, kind uint16
+		value     uint32
+	}{
+		{tiffImageWidth, tiffLong, uint32(m.C)},
+		{tiffImageLength, tiffLong, uint32(m.R)},
+		{tiffBitsPerSample, tiffShort, 1},
+		{tiffCompression, tiffShort, uint32(compression)},
+		{tiffPhotometric, tiffShort, tiffWhiteIsZero},
+		{tiffStripOffsets, tiffLong, 8},
+		{tiffSamplesPerPixel, tiffShort, 1},
+		{tiffRowsPerStrip, tiffLong, uint32(m.R)},
+		{tiffStripByteCounts, tiffLong, uint32(len(strip))},
+	}
+	// the strip, then the IFD word aligned
+	ifd := 8 + uint32(len(strip)+len(strip)%2)
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	b.WriteString("II")
+	binary.Write(&b, le, uint16(42))
+	binary.Write(&b, le, ifd)
+	b.Write(strip)
+	if len(strip)%2 == 1 {
+		b.WriteByte(0)
+	}
+	binary.Write(&b, le, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&b, le, e.tag)
+		binary.Write(&b, le, e.kind)
+		binary.Write(&b, le, uint32(1))
+		if e.kind == tiffShort {
+			binary.Write(&b, le, uint16(e.value))
+			binary.Write(&b, le, uint16(0))
+		} else {
+			binary.Write(&b, le, e.value)
+		}
+	}
+	binary.Write(&b, le, uint32(0))
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Reads the first image of either byte order
+func ReadTIFF(r io.Reader) (*MatrixBitSet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("tiff: too short for a header")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("tiff: unknown byte order %q", data[:2])
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("tiff: not a tiff")
+	}
+	tags, err := readTIFFTags(data, order, order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(tag uint16, fallback uint32) uint32 {
+		if values, ok := tags[tag]; ok && len(values) > 0 {
+			return values[0]
+		}
+		return fallback
+	}
+	width, height := uint(get(tiffImageWidth, 0)), uint(get(tiffImageLength, 0))
+	if get(tiffBitsPerSample, 1) != 1 || get(tiffSamplesPerPixel, 1) != 1 {
+		return nil, fmt.Errorf("tiff: not bilevel")
+	}
+	photometric := get(tiffPhotometric, tiffWhiteIsZero)
+	if photometric != tiffWhiteIsZero && photometric != tiffBlackIsZero {
+		return nil, fmt.Errorf("tiff: photometric %d isn't bilevel", photometric)
+	}
+	if len(tags[tiffStripOffsets]) != 1 || len(tags[tiffStripByteCounts]) != 1 {
+		return nil, fmt.Errorf("tiff: only single strip images are supported")
+	}
+	offset, count := get(tiffStripOffsets, 0), get(tiffStripByteCounts, 0)
+	if uint64(offset)+uint64(count) > uint64(len(data)) {
+		return nil, fmt.Errorf("tiff: strip runs past the end of the file")
+	}
+	strip := data[offset : offset+count]
+	if get(tiffFillOrder, 1) == 2 {
+		reversed := make([]byte, len(strip))
+		for i, b := range strip {
+			reversed[i] = bits.Reverse8(b)
+		}
+		strip = reversed
+	}
+
+	// nothing is allocated until the strip could hold the image
+	compression := TIFFCompression(get(tiffCompression, 1))
+	switch compression {
+	case TIFFUncompressed:
+		if err := checkDimensions(width, height); err != nil {
+			return nil, fmt.Errorf("tiff: %v", err)
+		}
+		if uint(len(strip)) < packedSize(width, height) {
+			return nil, fmt.Errorf("tiff: %d bytes for %d x %d", len(strip), height, width)
+		}
+	case TIFFGroup4:
+		if err := checkG4Size(len(strip), width, height); err != nil {
+			return nil, fmt.Errorf("tiff: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("tiff: compression %d isn't supported", compression)
+	}
+	m := NewMatrixBitSet(width, height)
+	if compression == TIFFUncompressed {
+		m.setPackedRows(strip, true)
+	} else if err := m.decodeG4(strip); err != nil {
+		return nil, err
+	}
+	if photometric == tiffBlackIsZero {
+		m.Invert()
+	}
+	return m, nil
+}
+
+// SHORT and LONG values of every tag in the IFD at offset
+func readTIFFTags(data []byte, order binary.ByteOrder, offset uint32) (map[uint16][]uint32, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, fmt.Errorf("tiff: IFD past the end of the file")
+	}
+	n := uint64(order.Uint16(data[offset:]))
+	if uint64(offset)+2+n*12 > uint64(len(data)) {
+		return nil, fmt.Errorf("tiff: IFD runs past the end of the file")
+	}
+	tags := make(map[uint16][]uint32)
+	for i := uint64(0); i < n; i++ {
+		entry := data[uint64(offset)+2+i*12:]
+		tag, kind, count := order.Uint16(entry), order.Uint16(entry[2:]), order.Uint32(entry[4:])
+		size := uint64(2)
+		if kind == tiffLong {
+			size = 4
+		} else if kind != tiffShort {
+			continue
+		}
+		values := entry[8:12]
+		if size*uint64(count) > 4 {
+			at := uint64(order.Uint32(entry[8:]))
+			if at+size*uint64(count) > uint64(len(data)) {
+				return nil, fmt.Errorf("tiff: tag %d values past the end of the file", tag)
+			}
+			values = data[at : at+size*uint64(count)]
+		}
+		for k := uint64(0); k < uint64(count); k++ {
+			if size == 2 {
+				tags[tag] = append(tags[tag], uint32(order.Uint16(values[k*2:])))
+			} else {
+				tags[tag] = append(tags[tag], order.Uint32(values[k*4:]))
+			}
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,111 @@
+package matrixbitset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func TestTIFFRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(31))
+	for _, compression := range []TIFFCompression{TIFFUncompressed, TIFFGroup4} {
+		for trial := 0; trial < 10; trial++ {
+			m := randomMatrix(rnd, uint(1+rnd.Intn(100)), uint(1+rnd.Intn(20)), 0.3)
+			var b bytes.Buffer
+			if err := m.WriteTIFF(&b, compression); err != nil {
+				t.Fatal(err)
+			}
+			back, err := ReadTIFF(&b)
+			if err != nil || !sameBits(back, m) {
+				t.Fatalf("Compression %d: round trip differs, %v", compression, err)
+			}
+		}
+	}
+	if err := NewMatrixBitSet(3, 3).WriteTIFF(&bytes.Buffer{}, 5); err == nil {
+		t.Error("Expected an error for LZW")
+	}
+}
+
+// A big endian BlackIsZero file with the bits in FillOrder 2
+func TestReadTIFFBigEndian(t *testing.T) {
+	var b bytes.Buffer
+	be := binary.BigEndian
+	b.WriteString("MM")
+	binary.Write(&b, be, uint16(42))
+	binary.Write(&b, be, uint32(10))
+	// 2 rows of 3, each padded to a byte: #.# then .##, inverted and bit reversed
+	b.Write([]byte{0x02, 0x01})
+	entries := [][3]uint32{
+		{tiffImageWidth, tiffShort, 3},
+		{tiffImageLength, tiffShort, 2},
+		{tiffBitsPerSample, tiffShort, 1},
+		{tiffCompression, tiffShort, 1},
+		{tiffPhotometric, tiffShort, tiffBlackIsZero},
+		{tiffFillOrder, tiffShort, 2},
+		{tiffStripOffsets, tiffLong, 8},
+		{tiffStripByteCounts, tiffLong, 2},
+	}
+	binary.Write(&b, be, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&b, be, uint16(e[0]))
+		binary.Write(&b, be, uint16(e[1]))
+		binary.Write(&b, be, uint32(1))
+		if e[1] == tiffShort {
+			binary.Write(&b, be, uint16(e[2]))
+			binary.Write(&b, be, uint16(0))
+		} else {
+			binary.Write(&b, be, e[2])
+		}
+	}
+	binary.Write(&b, be, uint32(0))
+
+	m, err := ReadTIFF(&b)
+	expected, _ := ParseASCII("#.#\n.##")
+	if err != nil || !sameBits(m, expected) {
+		t.Errorf("Unexpected matrix %v\n%v", err, m)
+	}
+}
+
+func TestReadTIFFErrors(t *testing.T) {
+	for _, bad := range [][]byte{
+		[]byte("II*"),
+		[]byte("XX*\x00\x08\x00\x00\x00"),
+		[]byte("II*\x00\xff\x00\x00\x00"),
+	} {
+		if _, err := ReadTIFF(bytes.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	var empty bytes.Buffer
+	if err := NewMatrixBitSet(3, 0).WriteTIFF(&empty, TIFFGroup4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTIFF(&empty); err == nil {
+		t.Error("Expected an error for a 0 x 3 image")
+	}
+	// a valid 8 x 8 file claiming a size its strip can't hold
+	for _, compression := range []TIFFCompression{TIFFUncompressed, TIFFGroup4} {
+		for _, size := range [][2]uint32{{1 << 26, 1 << 26}, {64, 1 << 20}} {
+			var b bytes.Buffer
+			if err := NewMatrixBitSet(8, 8).WriteTIFF(&b, compression); err != nil {
+				t.Fatal(err)
+			}
+			file := b.Bytes()
+			le := binary.LittleEndian
+			ifd := le.Uint32(file[4:])
+			for i := uint32(0); i < uint32(le.Uint16(file[ifd:])); i++ {
+				entry := file[ifd+2+i*12:]
+				switch le.Uint16(entry) {
+				case tiffImageWidth:
+					le.PutUint32(entry[8:], size[0])
+				case tiffImageLength, tiffRowsPerStrip:
+					le.PutUint32(entry[8:], size[1])
+				}
+			}
+			if _, err := ReadTIFF(bytes.NewReader(file)); err == nil {
+				t.Errorf("Compression %d: expected an error for %d x %d", compression, size[1], size[0])
+			}
+		}
+	}
+}
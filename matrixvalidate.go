@@ -0,0 +1,286 @@
+package matrixbitset
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Ring orientation as displayed, origin top left, matching Orient and Area2
+type Orientation int
+
+const (
+	// OGC's exterior orientation, holes go the other way
+	CounterClockwise Orientation = iota
+	Clockwise
+)
+
+type ViolationKind int
+
+const (
+	RingNotClosed ViolationKind = iota
+	RingTooShort
+	DuplicatePoint
+	SelfIntersection
+	HoleOutsideShell
+	WrongOrientation
+)
+
+var violationNames = map[ViolationKind]string{
+	RingNotClosed:    "ring not closed",
+	RingTooShort:     "ring too short",
+	DuplicatePoint:   "duplicate point",
+	SelfIntersection: "self intersection",
+	HoleOutsideShell: "hole outside shell",
+	WrongOrientation: "wrong orientation",
+}
+
+func (vk ViolationKind) String() string {
+	return violationNames[vk]
+}
+
+// Ring is -1 for the outer ring, otherwise the index into Holes
+type Violation struct {
+	Kind ViolationKind
+	Ring int
+	At   MatrixPos
+}
+
+func (v Violation) String() string {
+	if v.Ring < 0 {
+		return fmt.Sprintf("%s in outer ring at %v", v.Kind, v.At)
+	}
+	return fmt.Sprintf("%s in hole %d at %v", v.Kind, v.Ring, v.At)
+}
+
+// OGC simple feature rules with a counter clockwise outer ring
+// returns nothing for a valid polygon
+func (p *Polygon) Validate() []Violation {
+	return p.ValidateWith(CounterClockwise)
+}
+
+// Like Validate, with the outer ring expected to go the exterior way
+// and the holes the other way
+func (p *Polygon) ValidateWith(exterior Orientation) []Violation {
+	violations := make([]Violation, 0)
+	rings := p.rings()
+	for i, ring := range rings {
+		violations = append(violations, ring.violations(i-1, exterior, i > 0)...)
+	}
+	for i, hole := range p.Holes {
+		for _, pt := range hole {
+			if !p.Outer.containsPoint(pt.Both_i()) && !p.Outer.onRing(pt) {
+				violations = append(violations, Violation{Kind: HoleOutsideShell, Ring: i, At: pt})
+				break
+			}
+		}
+	}
+	// rings may touch, but not cross each other
+	for i := 0; i < len(rings); i++ {
+		for j := i + 1; j < len(rings); j++ {
+			if at, crossed := rings[i].crosses(rings[j]); crossed {
+				violations = append(violations, Violation{Kind: SelfIntersection, Ring: j - 1, At: at})
+			}
+		}
+	}
+	return violations
+}
+
+// Closes every ring, drops repeated and collinear points and turns the
+// outer ring counter clockwise, holes clockwise. Modifies p in place
+func (p *Polygon) Normalize() *Polygon {
+	return p.NormalizeWith(CounterClockwise)
+}
+
+// Like Normalize, with the outer ring turned the exterior way
+func (p *Polygon) NormalizeWith(exterior Orientation) *Polygon {
+	p.Outer = p.Outer.normalized(exterior)
+	for i, hole := range p.Holes {
+		p.Holes[i] = hole.normalized(1 - exterior)
+	}
+	return p
+}
+
+func (p *Polygon) rings() []LinearRing {
+	return append([]LinearRing{p.Outer}, p.Holes...)
+}
+
+func (lr LinearRing) violations(index int, exterior Orientation, hole bool) []Violation {
+	violations := make([]Violation, 0)
+	if len(lr) == 0 {
+		return append(violations, Violation{Kind: RingTooShort, Ring: index, At: InvalidPos})
+	}
+	if !samePoint(lr[0], lr[len(lr)-1]) {
+		violations = append(violations, Violation{Kind: RingNotClosed, Ring: index, At: lr[len(lr)-1]})
+	}
+	for i := 1; i < len(lr); i++ {
+		if samePoint(lr[i-1], lr[i]) {
+			violations = append(violations, Violation{Kind: DuplicatePoint, Ring: index, At: lr[i]})
+		}
+	}
+	if len(lr) < 4 {
+		return append(violations, Violation{Kind: RingTooShort, Ring: index, At: lr[0]})
+	}
+	if at, crossed := lr.selfIntersects(); crossed {
+		violations = append(violations, Violation{Kind: SelfIntersection, Ring: index, At: at})
+	}
+	want := exterior
+	if hole {
+		want = 1 - exterior
+	}
+	if area := lr.Area2(); (area > 0 && want == Clockwise) || (area < 0 && want == CounterClockwise) {
+		violations = append(violations, Violation{Kind: WrongOrientation, Ring: index, At: lr[0]})
+	}
+	return violations
+}
+
+// Sweeps the segments top to bottom so only pairs sharing rows and
+// cols are compared, touching counts. Adjacent segments only count
+// when one doubles back over the other
+func (lr LinearRing) selfIntersects() (MatrixPos, bool) {
+	n := len(lr) - 1
+	for i := 0; i < n; i++ {
+		next := (i + 1) % n
+		if orient(lr[i], lr[i+1], lr[next+1]) == 0 && !sameWay(lr[i], lr[i+1], lr[next+1]) &&
+			!samePoint(lr[i], lr[i+1]) && !samePoint(lr[next], lr[next+1]) {
+			return lr[i+1], true
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	top := func(i int) int {
+		return minInt(lr[i].Row_i(), lr[i+1].Row_i())
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return top(order[a]) < top(order[b])
+	})
+	for a, i := range order {
+		bottom := maxInt(lr[i].Row_i(), lr[i+1].Row_i())
+		left, right := minInt(lr[i].Col_i(), lr[i+1].Col_i()), maxInt(lr[i].Col_i(), lr[i+1].Col_i())
+		for _, j := range order[a+1:] {
+			if top(j) > bottom {
+				break
+			}
+			if j == i+1 || i == j+1 || (i == 0 && j == n-1) || (j == 0 && i == n-1) {
+				// neighbours share a point
+				continue
+			}
+			if minInt(lr[j].Col_i(), lr[j+1].Col_i()) > right || maxInt(lr[j].Col_i(), lr[j+1].Col_i()) < left {
+				continue
+			}
+			if segmentsIntersect(lr[i], lr[i+1], lr[j], lr[j+1]) {
+				return lr[maxInt(i, j)], true
+			}
+		}
+	}
+	return InvalidPos, false
+}
+
+// Proper crossings between two rings, shared points are fine
+func (lr LinearRing) crosses(other LinearRing) (MatrixPos, bool) {
+	for i := 0; i+1 < len(lr); i++ {
+		for j := 0; j+1 < len(other); j++ {
+			if segmentsCross(lr[i], lr[i+1], other[j], other[j+1]) {
+				return other[j], true
+			}
+		}
+	}
+	return InvalidPos, false
+}
+
+func (lr LinearRing) onRing(pt MatrixPos) bool {
+	for i := 0; i+1 < len(lr); i++ {
+		if orient(lr[i], lr[i+1], pt) == 0 && onSegment(lr[i], lr[i+1], pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// A new ring closed, without repeated or collinear points, oriented as asked
+func (lr LinearRing) normalized(orientation Orientation) LinearRing {
+	if len(lr) == 0 {
+		return lr
+	}
+	points := make([]MatrixPos, 0, len(lr))
+	for _, pt := range lr {
+		if len(points) == 0 || !samePoint(points[len(points)-1], pt) {
+			points = append(points, pt)
+		}
+	}
+	if len(points) > 1 && samePoint(points[0], points[len(points)-1]) {
+		points = points[:len(points)-1]
+	}
+	// one pass as a stack, removing a collinear point can
+	// make the one before it collinear with the next
+	kept := points[:0]
+	for _, pt := range points {
+		for len(kept) > 1 && orient(kept[len(kept)-2], kept[len(kept)-1], pt) == 0 {
+			kept = kept[:len(kept)-1]
+		}
+		if len(kept) == 0 || !samePoint(kept[len(kept)-1], pt) {
+			kept = append(kept, pt)
+		}
+	}
+	// then across the seam, trimming either end
+	start := 0
+	for len(kept)-start > 2 {
+		last := len(kept) - 1
+		if orient(kept[last-1], kept[last], kept[start]) == 0 {
+			kept = kept[:last]
+		} else if orient(kept[last], kept[start], kept[start+1]) == 0 {
+			start++
+		} else {
+			break
+		}
+	}
+	points = kept[start:]
+	ring := append(LinearRing(points), points[0])
+	if area := ring.Area2(); (area > 0 && orientation == Clockwise) || (area < 0 && orientation == CounterClockwise) {
+		ring.reverse()
+	}
+	return ring
+}
+
+func samePoint(a, b MatrixPos) bool {
+	return a.r == b.r && a.c == b.c
+}
+
+// pt is known collinear with a, b, is it within their extent?
+func onSegment(a, b, pt MatrixPos) bool {
+	return minInt(a.Row_i(), b.Row_i()) <= pt.Row_i() && pt.Row_i() <= maxInt(a.Row_i(), b.Row_i()) &&
+		minInt(a.Col_i(), b.Col_i()) <= pt.Col_i() && pt.Col_i() <= maxInt(a.Col_i(), b.Col_i())
+}
+
+// Including touching and collinear overlap
+func segmentsIntersect(p1, p2, q1, q2 MatrixPos) bool {
+	d1, d2 := orient(q1, q2, p1), orient(q1, q2, p2)
+	d3, d4 := orient(p1, p2, q1), orient(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// Only where each passes strictly through the other
+func segmentsCross(p1, p2, q1, q2 MatrixPos) bool {
+	d1, d2 := orient(q1, q2, p1), orient(q1, q2, p2)
+	d3, d4 := orient(p1, p2, q1), orient(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
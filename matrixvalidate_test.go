@@ -0,0 +1,123 @@
+package matrixbitset
+
+import (
+	"testing"
+)
+
+func ringOf(points ...[2]uint) LinearRing {
+	ring := make(LinearRing, 0, len(points))
+	for _, p := range points {
+		ring = append(ring, NewMatrixPos(p[0], p[1], 100))
+	}
+	return ring
+}
+
+func hasViolation(violations []Violation, kind ViolationKind, ring int) bool {
+	for _, v := range violations {
+		if v.Kind == kind && v.Ring == ring {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateExtracted(t *testing.T) {
+	m := NewMatrixBitSet(700, 700)
+	m.Fill(100, 100, 500, 500)
+	m.Drain(150, 150, 25, 25)
+	m.Fill(150, 50, 50, 50)
+	m.Fill(200, 600, 50, 50)
+	for _, tracer := range []Tracer{ShellTracer, SuzukiAbeTracer} {
+		polygons, _ := m.ExtractAllPolygons(WithTracer(tracer))
+		for _, p := range polygons {
+			if violations := p.Normalize().Validate(); len(violations) != 0 {
+				t.Errorf("tracer %d: expected a valid polygon, received %v", tracer, violations)
+			}
+		}
+	}
+	polygons, _ := m.ExtractCrackPolygons()
+	for _, p := range polygons {
+		if violations := p.Validate(); len(violations) != 0 {
+			t.Errorf("crack: expected a valid polygon, received %v", violations)
+		}
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	p := &Polygon{
+		// bow tie, not closed, repeated point
+		Outer: ringOf([2]uint{0, 0}, [2]uint{10, 10}, [2]uint{10, 10}, [2]uint{0, 10}, [2]uint{10, 0}),
+		Holes: []LinearRing{
+			// counter clockwise hole, well outside
+			ringOf([2]uint{50, 50}, [2]uint{60, 50}, [2]uint{60, 60}, [2]uint{50, 60}, [2]uint{50, 50}),
+		},
+	}
+	violations := p.Validate()
+	for _, expected := range []struct {
+		kind ViolationKind
+		ring int
+	}{
+		{RingNotClosed, -1},
+		{DuplicatePoint, -1},
+		{SelfIntersection, -1},
+		{HoleOutsideShell, 0},
+		{WrongOrientation, 0},
+	} {
+		if !hasViolation(violations, expected.kind, expected.ring) {
+			t.Errorf("Expected %s in ring %d, received %v", expected.kind, expected.ring, violations)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	p := &Polygon{
+		// clockwise with collinear points and a repeat
+		Outer: ringOf([2]uint{0, 0}, [2]uint{0, 5}, [2]uint{0, 10}, [2]uint{10, 10}, [2]uint{10, 10}, [2]uint{10, 0}, [2]uint{5, 0}),
+		Holes: []LinearRing{
+			ringOf([2]uint{2, 2}, [2]uint{8, 2}, [2]uint{8, 8}, [2]uint{2, 8}, [2]uint{2, 2}),
+		},
+	}
+	p.Normalize()
+	if len(p.Outer) != 5 {
+		t.Errorf("Expected 5 outer points, received %v", p.Outer)
+	}
+	if p.Outer.Area2() != 200 || p.Holes[0].Area2() != -72 {
+		t.Errorf("Expected areas 200, -72 received %d, %d", p.Outer.Area2(), p.Holes[0].Area2())
+	}
+	if violations := p.Validate(); len(violations) != 0 {
+		t.Errorf("Expected a valid polygon, received %v", violations)
+	}
+	p.NormalizeWith(Clockwise)
+	if violations := p.ValidateWith(Clockwise); len(violations) != 0 || p.Outer.Area2() > 0 {
+		t.Errorf("Expected a valid clockwise polygon, received %v", violations)
+	}
+}
+
+func TestNormalizeAcrossSeam(t *testing.T) {
+	// starts part way along the top edge, collinear runs on both sides of the seam
+	ring := ringOf([2]uint{0, 5}, [2]uint{0, 7}, [2]uint{0, 10}, [2]uint{5, 10}, [2]uint{10, 10},
+		[2]uint{10, 0}, [2]uint{0, 0}, [2]uint{0, 2}, [2]uint{0, 5}).normalized(CounterClockwise)
+	if len(ring) != 5 || ring.Area2() != 200 {
+		t.Errorf("Expected a 4 corner square of area 200, received %v", ring)
+	}
+	for i := 1; i+1 < len(ring); i++ {
+		if orient(ring[i-1], ring[i], ring[i+1]) == 0 {
+			t.Errorf("Collinear point %v left in %v", ring[i], ring)
+		}
+	}
+}
+
+func TestSelfIntersectsSweep(t *testing.T) {
+	// a staircase, valid
+	stairs := ringOf([2]uint{0, 0}, [2]uint{10, 0}, [2]uint{10, 10}, [2]uint{8, 10}, [2]uint{8, 8},
+		[2]uint{6, 8}, [2]uint{6, 6}, [2]uint{4, 6}, [2]uint{4, 4}, [2]uint{2, 4}, [2]uint{2, 2}, [2]uint{0, 2}, [2]uint{0, 0})
+	if at, crossed := stairs.selfIntersects(); crossed {
+		t.Errorf("Expected the staircase not to intersect itself, received %v", at)
+	}
+	// the last step folds back to touch the first edge far from its neighbours
+	folded := ringOf([2]uint{0, 0}, [2]uint{10, 0}, [2]uint{10, 10}, [2]uint{8, 10}, [2]uint{8, 8},
+		[2]uint{6, 8}, [2]uint{6, 0}, [2]uint{4, 4}, [2]uint{0, 4}, [2]uint{0, 0})
+	if at, crossed := folded.selfIntersects(); !crossed || at != NewMatrixPos(6, 0, 100) {
+		t.Errorf("Expected a touch at [6, 0], received %v %v", at, crossed)
+	}
+}